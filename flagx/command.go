@@ -1,19 +1,18 @@
 package flagx
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	floatx2 "github.com/hsiafan/glow/mathx/floatx"
-	intx2 "github.com/hsiafan/glow/mathx/intx"
 	"github.com/hsiafan/glow/reflectx"
 	"github.com/hsiafan/glow/stringx"
 	"github.com/hsiafan/glow/stringx/ascii"
+	"io"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
-	"time"
 )
 
 const (
@@ -24,27 +23,80 @@ const (
 	indexField        = "index"       // the position of non-flag args, start from 0. If a field with slice type, set args true, and leave index not set, will receive all non-consumed non-flag args.
 	ignoreFiled       = "ignore"      // for ignore one struct field
 	requiredField     = "required"    // If this args is required(true|false). Default is false. If default value is set, the required filed will be ignored.
+	envField          = "env"         // the environment variable name, used as value if the flag arg is not set
+	configFileField   = "configfile"  // mark a string flag as config file path, the tag value is the format: json, toml, ini or auto
+	completeField     = "complete"    // the shell completion for arg value: file, dir, or a method name of option struct
+	layoutField       = "layout"      // the layout for parsing time.Time value
+	choicesField      = "choices"     // the allowed values, separated by comma
+	minField          = "min"         // the min value, for numeric and duration type
+	maxField          = "max"         // the max value, for numeric and duration type
+	patternField      = "pattern"     // the regexp pattern the value should match
+	existsField       = "exists"      // the value should be a path of existing file or dir: file|dir
+	exclusiveField    = "exclusive"   // the mutually exclusive group names, separated by comma
+	togetherField     = "together"    // the group names, separated by comma, in which flag args should be set together
+	shortField        = "short"       // the one char short alias of flag name
+	groupField        = "group"       // the group name for showing flag args in usage message
+)
+
+const (
+	existsFile = "file"
+	existsDir  = "dir"
 )
 
 // Handle is alias command handle function
 type Handle = func() error
 
+// ContextHandle is command handle function, with the context passed to Execute
+type ContextHandle = func(ctx context.Context) error
+
 // Command is a command line
 type Command struct {
-	Name             string                      // the name of this command
-	Description      string                      // usage message
-	parentCmd        string                      // the composite command name, if this is a sub command
-	flagSet          *flag.FlagSet               // for internal process
+	optionFields
+	Name        string            // the name of this command
+	Aliases     []string          // the alias names of this command, when it is a sub command
+	Description string            // usage message
+	Stdout      io.Writer         // for usage and completion output. If not set, use the parent's, or os.Stdout
+	Stderr      io.Writer         // for error output. If not set, use the parent's, or os.Stderr
+	Syntax      Syntax            // the syntax for parsing args. If not set, use the parent's, or SyntaxGo
+	Color       ColorMode         // if usage message is colored. If not set, use the parent's, or color when output is a terminal
+	parent      *CompositeCommand // the composite command, if this is a sub command
+	inherited   []*fieldFlagValue // persistent flag fields inherited from ancestor composite commands
+	handle      ContextHandle
+}
+
+// the arg fields parsed from option struct
+type optionFields struct {
 	flagFields       []*fieldFlagValue           // flag field
 	positionalFields map[int]*positionalArgField // for storing positional non-flag args field
 	remainFields     *remainedArgsField          // for storing remained non-flag args
-	handle           Handle
+	groups           []*fieldGroup               // flag arg groups
 }
 
 // NewCommand create new command
 func NewCommand(Name string, Description string, option interface{}, handle Handle) (*Command, error) {
-	flagSet := &flag.FlagSet{}
+	return NewContextCommand(Name, Description, option, func(ctx context.Context) error {
+		return handle()
+	})
+}
+
+// NewContextCommand create new command, with a handle receive the context passed to ExecuteContext
+func NewContextCommand(Name string, Description string, option interface{}, handle ContextHandle) (*Command, error) {
+	fields, err := parseOptionFields(option)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &Command{
+		optionFields: *fields,
+		Name:         Name,
+		Description:  Description,
+		handle:       handle,
+	}
+	return cmd, nil
+}
 
+// parse option struct, to get arg fields
+func parseOptionFields(option interface{}) (*optionFields, error) {
 	v := reflect.ValueOf(option)
 	if v.IsValid() == false {
 		return nil, errors.New("not valid option value")
@@ -62,28 +114,40 @@ func NewCommand(Name string, Description string, option interface{}, handle Hand
 		return nil, errors.New("option should be a struct")
 	}
 
-	var fieldFlagValues []*fieldFlagValue
-	var positionalArgFields = map[int]*positionalArgField{}
-	var raf *remainedArgsField
+	fields := &optionFields{
+		positionalFields: map[int]*positionalArgField{},
+	}
+	if err := fields.addStructFields(reflect.ValueOf(option), v, "", false); err != nil {
+		return nil, err
+	}
+
+	// check if all non-flag args is consumed
+	for idx := 0; idx < len(fields.positionalFields); idx++ {
+		if _, ok := fields.positionalFields[idx]; !ok {
+			return nil, fmt.Errorf("no field receive %v-th non-flag arg", idx)
+		}
+	}
+	return fields, nil
+}
 
+// add arg fields of a struct. For nested struct, the flag names are prefixed with prefix.
+func (o *optionFields) addStructFields(option reflect.Value, v reflect.Value, prefix string, nested bool) error {
 	for i := 0; i < v.NumField(); i++ {
 		fieldValue := v.Field(i)
 		fieldType := v.Type().Field(i)
 
 		ignore, err := reflectx.GetBoolTagValue(fieldType.Tag, ignoreFiled, false)
 		if err != nil {
-			return nil, errors.New("invalid bool value for ignore tag of field:" + fieldType.Name)
+			return errors.New("invalid bool value for ignore tag of field:" + fieldType.Name)
 		}
 		if ignore {
 			continue
 		}
 
 		if fieldValue.IsValid() == false || fieldValue.CanSet() == false {
-			return nil, fmt.Errorf("invalid field %v", fieldType.Name)
+			return fmt.Errorf("invalid field %v", fieldType.Name)
 		}
 
-		//TODO: check field type
-
 		var flagName string
 		tagName := fieldType.Tag.Get(nameField)
 		if tagName != "" {
@@ -94,178 +158,432 @@ func NewCommand(Name string, Description string, option interface{}, handle Hand
 
 		isFlagArg, err := reflectx.GetBoolTagValue(fieldType.Tag, flagField, true)
 		if err != nil {
-			return nil, fmt.Errorf("struct tag of flag is not valid, field name: %v", fieldType.Name)
+			return fmt.Errorf("struct tag of flag is not valid, field name: %v", fieldType.Name)
 		}
+
+		if isFlagArg && isNestedStructType(fieldType.Type) {
+			// embedded struct without name tag, do not add prefix
+			nestedPrefix := prefix
+			if !fieldType.Anonymous || tagName != "" {
+				nestedPrefix = prefix + flagName + "-"
+			}
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(fieldType.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if err := o.addStructFields(option, fieldValue, nestedPrefix, true); err != nil {
+				return err
+			}
+			continue
+		}
+		flagName = prefix + flagName
+
 		required, err := reflectx.GetBoolTagValue(fieldType.Tag, requiredField, false)
 		if err != nil {
-			return nil, fmt.Errorf("struct tag of required is not valid, field name: %v", fieldType.Name)
+			return fmt.Errorf("struct tag of required is not valid, field name: %v", fieldType.Name)
+		}
+		completer, err := getCompleter(option, fieldType.Tag.Get(completeField))
+		if err != nil {
+			return fmt.Errorf("struct tag of complete is not valid, field name: %v, error: %w", fieldType.Name, err)
+		}
+		layout := fieldType.Tag.Get(layoutField)
+		validators, err := newValidators(fieldType)
+		if err != nil {
+			return fmt.Errorf("invalid validation tag for field %v: %w", fieldType.Name, err)
 		}
 
 		if !isFlagArg {
+			if nested {
+				return fmt.Errorf("non-flag arg field %v can not be in nested struct", fieldType.Name)
+			}
 			sv, hasIndex := fieldType.Tag.Lookup(indexField)
 			if hasIndex {
 				index, err := strconv.Atoi(sv)
 				if err != nil || index < 0 {
-					return nil, fmt.Errorf("illegal index value %v for field %v", sv, fieldType.Name)
+					return fmt.Errorf("illegal index value %v for field %v", sv, fieldType.Name)
 				}
 
-				if _, ok := positionalArgFields[index]; ok {
-					return nil, fmt.Errorf("field %v and %v have same index %v", positionalArgFields[index].fieldName, fieldType.Name, index)
+				if _, ok := o.positionalFields[index]; ok {
+					return fmt.Errorf("field %v and %v have same index %v", o.positionalFields[index].fieldName, fieldType.Name, index)
 				}
-				if _, ok := positionalArgFields[index]; ok {
-					if index == -1 {
-						return nil, fmt.Errorf("both fields %v and %v want to receive all remains args", positionalArgFields[index].fieldName, fieldType.Name)
-					} else {
-						return nil, fmt.Errorf("both fields %v and %v have same index %v", positionalArgFields[index].fieldName, fieldType.Name, index)
-					}
+				if err := checkValueType(fieldType.Type); err != nil {
+					return fmt.Errorf("invalid type for field %v: %w", fieldType.Name, err)
 				}
-				positionalArgFields[index] = &positionalArgField{
-					value:     fieldValue,
-					_type:     fieldType.Type,
-					fieldName: fieldType.Name,
-					name:      flagName,
-					required:  required,
-					index:     index,
+				o.positionalFields[index] = &positionalArgField{
+					value:       fieldValue,
+					initial:     copyValue(fieldValue),
+					_type:       fieldType.Type,
+					fieldName:   fieldType.Name,
+					name:        flagName,
+					description: fieldType.Tag.Get(descriptionField),
+					required:    required,
+					index:       index,
+					layout:      layout,
+					completer:   completer,
+					validators:  validators,
 				}
 			} else {
 				if fieldType.Type.Kind() != reflect.Slice {
-					return nil, fmt.Errorf("field %v want to receive all remains args, but is not slice type", fieldType.Name)
+					return fmt.Errorf("field %v want to receive all remains args, but is not slice type", fieldType.Name)
+				}
+				if err := checkValueType(fieldType.Type.Elem()); err != nil {
+					return fmt.Errorf("invalid type for field %v: %w", fieldType.Name, err)
 				}
-				if raf != nil {
-					return nil, fmt.Errorf("both fields %v and %v want to receive remained non-flag args", raf.fieldName, fieldType.Name)
+				if o.remainFields != nil {
+					return fmt.Errorf("both fields %v and %v want to receive remained non-flag args", o.remainFields.fieldName, fieldType.Name)
 				} else {
-					raf = &remainedArgsField{
-						value:     fieldValue,
-						_type:     fieldType.Type,
-						fieldName: fieldType.Name,
-						name:      flagName,
+					o.remainFields = &remainedArgsField{
+						value:       fieldValue,
+						initial:     copyValue(fieldValue),
+						_type:       fieldType.Type,
+						fieldName:   fieldType.Name,
+						name:        flagName,
+						description: fieldType.Tag.Get(descriptionField),
+						layout:      layout,
+						completer:   completer,
+						validators:  validators,
 					}
 				}
 			}
 			continue
 		} else {
+			if err := checkFieldType(fieldType.Type); err != nil {
+				return fmt.Errorf("invalid type for field %v: %w", fieldType.Name, err)
+			}
 			defaultValue, hasDefault := fieldType.Tag.Lookup(defaultValueField)
+			short := fieldType.Tag.Get(shortField)
+			if short != "" && (len(short) != 1 || short == "-" || short == "=") {
+				return fmt.Errorf("short name %v of field %v should be one char", short, fieldType.Name)
+			}
 			var ffv = &fieldFlagValue{
 				name:         flagName,
+				short:        short,
+				group:        fieldType.Tag.Get(groupField),
 				value:        fieldValue,
 				defaultValue: defaultValue,
+				hasDefault:   hasDefault,
+				fieldName:    fieldType.Name,
+				env:          fieldType.Tag.Get(envField),
+				description:  fieldType.Tag.Get(descriptionField),
+				layout:       layout,
 				_type:        fieldType.Type,
 				required:     required,
+				completer:    completer,
+				validators:   validators,
 			}
-			fieldFlagValues = append(fieldFlagValues, ffv)
-			description := fieldType.Tag.Get(descriptionField)
+			for _, f := range o.flagFields {
+				if f.name == flagName {
+					return fmt.Errorf("fields %v and %v have same flag name %v", f.fieldName, fieldType.Name, flagName)
+				}
+				if short != "" && (f.short == short || f.name == short) || f.short != "" && f.short == flagName {
+					return fmt.Errorf("fields %v and %v have same flag name %v", f.fieldName, fieldType.Name, short)
+				}
+			}
+			if format, ok := fieldType.Tag.Lookup(configFileField); ok {
+				if fieldType.Type.Kind() != reflect.String {
+					return fmt.Errorf("config file field %v should be string type", fieldType.Name)
+				}
+				for _, f := range o.flagFields {
+					if f.configFile {
+						return fmt.Errorf("both fields %v and %v are config file field", f.fieldName, fieldType.Name)
+					}
+				}
+				ffv.configFile = true
+				ffv.configFormat = format
+			}
+			o.flagFields = append(o.flagFields, ffv)
+			o.addToGroups(ffv, fieldType)
 			if hasDefault {
-				err := setValue(defaultValue, fieldType.Type.Kind(), fieldValue)
-				if err != nil {
-					return nil, fmt.Errorf("invalid default value for field %v, error: %w", fieldType.Name, err)
+				if err := ffv.setDefault(); err != nil {
+					return fmt.Errorf("invalid default value for field %v, error: %w", fieldType.Name, err)
 				}
 			}
-			flagSet.Var(ffv, flagName, description)
+			ffv.initial = copyValue(fieldValue)
 		}
 	}
+	return nil
+}
 
-	// check if all non-flag args is consumed
-	for idx := 0; idx < len(positionalArgFields); idx++ {
-		if _, ok := positionalArgFields[idx]; !ok {
-			return nil, fmt.Errorf("no field receive %v-th non-flag arg", idx)
-		}
+// ParseOsArgsAndExecute parse commandline passed arguments, and run handlers.If error occurred, will exit with non-zero code.
+func (c *Command) ParseOsArgsAndExecute() {
+	c.ParseAndExecute(os.Args[1:])
+}
+
+// ParseAndExecute parse arguments, and run handlers. If error occurred, will exit with non-zero code.
+func (c *Command) ParseAndExecute(arguments []string) {
+	if err := c.Execute(arguments); err != nil && err != ErrHelp {
+		c.exitOnError(err)
 	}
+}
 
-	cmd := &Command{
-		flagSet:          flagSet,
-		flagFields:       fieldFlagValues,
-		positionalFields: positionalArgFields,
-		remainFields:     raf,
-		Name:             Name,
-		Description:      Description,
-		handle:           handle,
-	}
-
-	flagSet.Usage = func() {
-		output := flagSet.Output()
-		if cmd.Description != "" {
-			_, _ = fmt.Fprintln(output, cmd.Description+"\n")
-		}
+// Execute parse arguments, and run handler. Errors are returned instead of exiting the process.
+// The parse errors are typed errors, such as *UnknownFlagError, *RequiredArgError, *InvalidValueError, and *UnhandledArgsError.
+// If help is requested, the usage message is shown, and ErrHelp is returned.
+func (c *Command) Execute(arguments []string) error {
+	return c.ExecuteContext(context.Background(), arguments)
+}
 
-		argDes := argsDesc(raf, positionalArgFields)
-		if cmd.parentCmd != "" {
-			_, _ = fmt.Fprintf(output, "Usage: %s %s %s\n", cmd.parentCmd, cmd.Name, argDes)
-		} else {
-			_, _ = fmt.Fprintf(output, "Usage: %s %s\n", cmd.Name, argDes)
-		}
+// ExecuteContext is the same as Execute, and the ctx is passed to command handle.
+func (c *Command) ExecuteContext(ctx context.Context, arguments []string) error {
+	_, err := c.execute(ctx, arguments)
+	return err
+}
 
-		flagSet.PrintDefaults()
+func (c *Command) execute(ctx context.Context, arguments []string) (commandNode, error) {
+	if c.parent == nil && len(arguments) > 0 && arguments[0] == completeCommandName {
+		printCompletions(c.stdout(), c, arguments[1:])
+		return c, nil
+	}
+	if err := c.parse(arguments); err != nil {
+		return c, err
 	}
+	return c, c.handle(ctx)
+}
 
-	return cmd, nil
+// parse arguments, and set values to option struct
+func (c *Command) parse(arguments []string) error {
+	c.inheritFlags()
+	c.reset()
+	args, err := parseArgs(arguments, c.allFlagFields(), c.syntax(), true)
+	if err == ErrHelp {
+		c.printUsage(c.stdout())
+		return ErrHelp
+	}
+	// collect parse errors, and report them together with validation errors
+	var errs []error
+	flagsParsed := err == nil
+	if err != nil {
+		errs = append(errs, err)
+	}
 
+	if err := c.setFallbackValues(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// non-flag args are unknown if flag args parsing failed
+	if flagsParsed {
+		for i, arg := range args {
+			if i >= len(c.positionalFields) {
+				break
+			}
+			if err := c.positionalFields[i].Set(arg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if len(args) > len(c.positionalFields) {
+			remainArgs := args[len(c.positionalFields):]
+			if c.remainFields == nil {
+				errs = append(errs, &UnhandledArgsError{Args: remainArgs})
+			} else if err := c.remainFields.Set(remainArgs); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return c.validate(errs, flagsParsed)
 }
 
-// ParseOsArgsAndExecute parse commandline passed arguments, and run handlers.If error occurred, will exit with non-zero code.
-func (c *Command) ParseOsArgsAndExecute() {
-	c.ParseAndExecute(os.Args[1:])
+// reset arg fields of this command to initial values, so the command can be executed again.
+// Inherited persistent flags are reset by the composite command which owns them.
+func (c *Command) reset() {
+	for _, f := range c.flagFields {
+		f.reset()
+	}
+	for _, f := range c.positionalFields {
+		f.reset()
+	}
+	if c.remainFields != nil {
+		c.remainFields.reset()
+	}
 }
 
-// ParseAndExecute parse arguments, and run handlers. If error occurred, will exit with non-zero code.
-func (c *Command) ParseAndExecute(arguments []string) {
-	if err := c.flagSet.Parse(arguments); err != nil {
-		if err == flag.ErrHelp {
-			// already show usage
-			return
+// set values from env and config file, for flag args not set by command line.
+// The precedence is: flag > env > config file > default.
+func (c *Command) setFallbackValues() error {
+	fields := c.allFlagFields()
+	var config configValues
+	for _, field := range fields {
+		if !field.configFile {
+			continue
+		}
+		if err := c.setEnvValue(field); err != nil {
+			return err
+		}
+		if path := field.value.String(); path != "" {
+			var err error
+			if config, err = loadConfigFile(path, field.configFormat); err != nil {
+				return fmt.Errorf("load config file %v error: %w", path, err)
+			}
+			// only the nearest config file is used
+			break
 		}
-		c.exitOnError(fmt.Errorf("parse flag error: %w", err))
-		return
 	}
 
-	for _, field := range c.flagFields {
-		if field.required && !field.set {
-			c.exitOnError(fmt.Errorf("flag arg %v required but not set", field.name))
+	for _, field := range fields {
+		if err := c.setEnvValue(field); err != nil {
+			return err
+		}
+		if field.set {
+			continue
+		}
+		for _, value := range config[field.name] {
+			if err := field.Set(value); err != nil {
+				return fmt.Errorf("set value from config file error: %w", err)
+			}
 		}
 	}
+	return nil
+}
 
-	for _, field := range c.positionalFields {
-		if field.required && !field.set {
-			c.exitOnError(fmt.Errorf("non-flag arg %v[%v] required but not set", field.name, field.index))
+// set flag value from env, if flag arg not set and env is present.
+// For slice type, the env value is split by comma.
+func (c *Command) setEnvValue(field *fieldFlagValue) error {
+	if field.set || field.env == "" {
+		return nil
+	}
+	envValue, ok := os.LookupEnv(field.env)
+	if !ok {
+		return nil
+	}
+	values := []string{envValue}
+	if isMultiValueType(field._type) {
+		values = strings.Split(envValue, ",")
+	}
+	for _, value := range values {
+		if err := field.Set(value); err != nil {
+			return fmt.Errorf("set value from env %v error: %w", field.env, err)
 		}
 	}
+	return nil
+}
+
+// ShowUsage print formatted usage message
+func (c *Command) ShowUsage() {
+	c.printUsage(c.stdout())
+}
+
+func (c *Command) printUsage(w io.Writer) {
+	c.inheritFlags()
+	uw := newUsageWriter(w, c.colorMode())
+	if c.Description != "" {
+		uw.paragraph(c.Description)
+	}
+	uw.synopsis(c.path(), argsDesc(c.remainFields, c.positionalFields))
 
-	// deal with positional args
-	args := c.flagSet.Args()
-	for i, arg := range args {
-		if i >= len(c.positionalFields) {
-			break
-		}
-		af := c.positionalFields[i]
-		if err := af.Set(arg); err != nil {
-			c.exitOnError(fmt.Errorf("set positional arguments error: %w", err))
-			return
+	var argRows []usageRow
+	for idx := 0; idx < len(c.positionalFields); idx++ {
+		f := c.positionalFields[idx]
+		desc := f.description
+		if f.required {
+			desc = stringx.AppendIfNotEmpty(desc, " ") + "(required)"
 		}
+		argRows = append(argRows, usageRow{name: f.name, typeName: valueTypeName(f._type), desc: desc})
+	}
+	if f := c.remainFields; f != nil {
+		argRows = append(argRows, usageRow{name: f.name + "...", typeName: valueTypeName(f._type), desc: f.description})
 	}
+	uw.section("Arguments", argRows)
+	uw.flagSections("Flags", c.flagFields, c.syntax())
+	uw.flagSections("Global Flags", c.inherited, c.syntax())
+}
 
-	if len(args) > len(c.positionalFields) {
-		remainArgs := args[len(c.positionalFields):]
-		if c.remainFields == nil {
-			c.exitOnError(fmt.Errorf("still has args not handled: %v", remainArgs))
-		}
-		if err := c.remainFields.Set(remainArgs); err != nil {
-			c.exitOnError(err)
-			return
+func (c *Command) stdout() io.Writer {
+	if c.Stdout != nil {
+		return c.Stdout
+	}
+	if c.parent != nil {
+		return c.parent.stdout()
+	}
+	return os.Stdout
+}
+
+func (c *Command) stderr() io.Writer {
+	if c.Stderr != nil {
+		return c.Stderr
+	}
+	if c.parent != nil {
+		return c.parent.stderr()
+	}
+	return os.Stderr
+}
+
+// path return the full command path, from the root command
+func (c *Command) path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.path() + " " + c.Name
+}
+
+// the syntax for parsing args
+func (c *Command) syntax() Syntax {
+	if c.Syntax != SyntaxDefault {
+		return c.Syntax
+	}
+	if c.parent != nil {
+		return c.parent.syntax()
+	}
+	return SyntaxGo
+}
+
+// the color mode for usage message
+func (c *Command) colorMode() ColorMode {
+	if c.Color == ColorAuto && c.parent != nil {
+		return c.parent.colorMode()
+	}
+	return c.Color
+}
+
+// collect persistent flags of ancestor composite commands.
+// If a flag of this command has the same name, the persistent flag is shadowed.
+func (c *Command) inheritFlags() {
+	c.inherited = nil
+	if c.parent == nil {
+		return
+	}
+	for _, ffv := range c.parent.allPersistentFields() {
+		if findField(c.flagFields, ffv.name, false) != nil || findField(c.inherited, ffv.name, false) != nil {
+			continue
 		}
+		c.inherited = append(c.inherited, ffv)
 	}
+}
 
-	if err := c.handle(); err != nil {
-		c.exitOnError(err)
+// all flag fields, including inherited persistent flag fields
+func (c *Command) allFlagFields() []*fieldFlagValue {
+	if len(c.inherited) == 0 {
+		return c.flagFields
 	}
+	fields := make([]*fieldFlagValue, 0, len(c.flagFields)+len(c.inherited))
+	fields = append(fields, c.flagFields...)
+	return append(fields, c.inherited...)
 }
 
-// ShowUsage print formatted usage message
-func (c *Command) ShowUsage() {
-	c.flagSet.Usage()
+func (c *Command) nodeName() string {
+	return c.Name
+}
+
+func (c *Command) nodeAliases() []string {
+	return c.Aliases
+}
+
+func (c *Command) nodeDescription() string {
+	return c.Description
+}
+
+func (c *Command) getParent() *CompositeCommand {
+	return c.parent
+}
+
+func (c *Command) setParent(parent *CompositeCommand) {
+	c.parent = parent
 }
 
 func (c *Command) exitOnError(err error) {
-	fmt.Println(err)
-	c.ShowUsage()
+	_, _ = fmt.Fprintln(c.stderr(), err)
+	c.printUsage(c.stderr())
 	os.Exit(-1)
 }
 
@@ -309,11 +627,39 @@ var _ flag.Value = (*fieldFlagValue)(nil)
 // flag.Value implementation that store value in a struct field
 type fieldFlagValue struct {
 	name         string
+	short        string // the one char short alias, may be empty
+	group        string // the group name in usage message
 	defaultValue string
+	hasDefault   bool
+	env          string    // the environment variable name
+	description  string    // the usage message
+	configFile   bool      // if this flag is the config file path
+	configFormat string    // the config file format
+	completer    completer // provide shell completion candidates for value, may be nil
+	layout       string    // the layout for time value
+	validators   []validator
+	fieldName    string // struct field name
 	_type        reflect.Type
 	value        reflect.Value
+	initial      reflect.Value // the initial value, with default value set
 	required     bool
 	set          bool
+	setErr       error // the last error when set value, for getting typed error after flag set parsing
+}
+
+// reset to the initial state before parsing
+func (f *fieldFlagValue) reset() {
+	f.set = false
+	f.setErr = nil
+	f.value.Set(copyValue(f.initial))
+}
+
+// usage message for flag set
+func (f *fieldFlagValue) usage() string {
+	if f.env == "" {
+		return f.description
+	}
+	return stringx.AppendIfNotEmpty(f.description, " ") + "(env $" + f.env + ")"
 }
 
 func (f *fieldFlagValue) String() string {
@@ -321,154 +667,124 @@ func (f *fieldFlagValue) String() string {
 }
 
 func (f *fieldFlagValue) Set(s string) error {
-	if f.set && f._type.Kind() != reflect.Slice {
-		return errors.New("flag arg " + f.name + " already set")
+	f.setErr = f.set0(s)
+	return f.setErr
+}
+
+func (f *fieldFlagValue) set0(s string) error {
+	if !isMultiValueType(f._type) {
+		if f.set {
+			return &InvalidValueError{Name: f.name, Field: f.fieldName, Value: s, Err: errors.New("flag arg already set")}
+		}
+		if err := setValue(s, f.value, f.layout); err != nil {
+			return &InvalidValueError{Name: f.name, Field: f.fieldName, Value: s, Err: err}
+		}
+		f.set = true
+		return nil
+	}
+	if !f.set && f.hasDefault {
+		// replace the default values
+		f.value.Set(reflect.Zero(f._type))
+	}
+	if err := addValue(s, f.value, f.layout); err != nil {
+		return &InvalidValueError{Name: f.name, Field: f.fieldName, Value: s, Err: err}
 	}
 	f.set = true
-	if f._type.Kind() != reflect.Slice {
-		return setValue(s, f._type.Kind(), f.value)
+	return nil
+}
+
+// set the default value. For slice and map type, the default value is split by comma.
+func (f *fieldFlagValue) setDefault() error {
+	if !isMultiValueType(f._type) {
+		return setValue(f.defaultValue, f.value, f.layout)
 	}
-	newValue := reflect.New(f._type.Elem())
-	if err := setValue(s, f._type.Elem().Kind(), newValue.Elem()); err != nil {
-		return fmt.Errorf("set %v argument error: %w", f.name, err)
+	for _, s := range strings.Split(f.defaultValue, ",") {
+		if err := addValue(s, f.value, f.layout); err != nil {
+			return err
+		}
 	}
-	f.value.Set(reflect.Append(f.value, newValue.Elem()))
 	return nil
 }
 
 func (f *fieldFlagValue) IsBoolFlag() bool {
-	return f._type.Kind() == reflect.Bool
+	return isBoolType(f._type)
 }
 
 // for remember non-flag positional args.
 type positionalArgField struct {
-	value     reflect.Value
-	_type     reflect.Type
-	fieldName string // struct field name
-	name      string // flag name
-	index     int    // non-flag arg index
-	required  bool
-	set       bool
+	value       reflect.Value
+	initial     reflect.Value
+	_type       reflect.Type
+	fieldName   string // struct field name
+	name        string // flag name
+	description string // the usage message
+	index       int    // non-flag arg index
+	layout      string // the layout for time value
+	required    bool
+	set         bool
+	setErr      error // the error when set value
+	completer   completer
+	validators  []validator
+}
+
+func (f *positionalArgField) reset() {
+	f.set = false
+	f.setErr = nil
+	f.value.Set(copyValue(f.initial))
 }
 
 func (f *positionalArgField) Set(s string) error {
+	if err := setValue(s, f.value, f.layout); err != nil {
+		f.setErr = &InvalidValueError{Name: f.name, Field: f.fieldName, Value: s, Err: err}
+		return f.setErr
+	}
 	f.set = true
-	return setValue(s, f._type.Kind(), f.value)
+	return nil
 }
 
 // for remember non-flag remained args.
 type remainedArgsField struct {
-	value     reflect.Value
-	_type     reflect.Type
-	fieldName string // struct field name
-	name      string // flag name
+	value       reflect.Value
+	initial     reflect.Value
+	_type       reflect.Type
+	fieldName   string // struct field name
+	name        string // flag name
+	description string // the usage message
+	layout      string // the layout for time value
+	completer   completer
+	validators  []validator
+}
+
+func (f *remainedArgsField) reset() {
+	f.value.Set(copyValue(f.initial))
 }
 
 func (f *remainedArgsField) Set(args []string) error {
 	slice := reflect.MakeSlice(f._type, len(args), len(args))
 	for idx, arg := range args {
-		if err := setValue(arg, f._type.Elem().Kind(), slice.Index(idx)); err != nil {
-			return fmt.Errorf("set remained arguments error: %w", err)
+		if err := setValue(arg, slice.Index(idx), f.layout); err != nil {
+			return &InvalidValueError{Name: f.name, Field: f.fieldName, Value: arg, Err: err}
 		}
 	}
 	f.value.Set(slice)
 	return nil
 }
 
-// set one arg field, the value converted from str input
-func setValue(str string, kind reflect.Kind, value reflect.Value) error {
-	switch kind {
-	case reflect.String:
-		value.SetString(str)
-	case reflect.Int:
-		v, err := intx2.Parse(str)
-		if err != nil {
-			return err
-		}
-		value.SetInt(int64(v))
-	case reflect.Int8:
-		v, err := intx2.Parse8(str)
-		if err != nil {
-			return err
-		}
-		value.SetInt(int64(v))
-	case reflect.Int16:
-		v, err := intx2.Parse16(str)
-		if err != nil {
-			return err
-		}
-		value.SetInt(int64(v))
-	case reflect.Int32:
-		v, err := intx2.Parse32(str)
-		if err != nil {
-			return err
-		}
-		value.SetInt(int64(v))
-	case reflect.Int64:
-		switch value.Interface().(type) {
-		case time.Duration:
-			v, err := time.ParseDuration(str)
-			if err != nil {
-				return err
-			}
-			value.Set(reflect.ValueOf(v))
-			return nil
-		}
-		v, err := intx2.Parse64(str)
-		if err != nil {
-			return err
-		}
-		value.SetInt(v)
-	case reflect.Uint:
-		v, err := intx2.ParseUnsigned(str)
-		if err != nil {
-			return err
-		}
-		value.SetUint(uint64(v))
-	case reflect.Uint8:
-		v, err := intx2.ParseUnsigned8(str)
-		if err != nil {
-			return err
-		}
-		value.SetUint(uint64(v))
-	case reflect.Uint16:
-		v, err := intx2.ParseUnsigned16(str)
-		if err != nil {
-			return err
-		}
-		value.SetUint(uint64(v))
-	case reflect.Uint32:
-		v, err := intx2.ParseUnsigned32(str)
-		if err != nil {
-			return err
-		}
-		value.SetUint(uint64(v))
-	case reflect.Uint64:
-		v, err := intx2.ParseUnsigned64(str)
-		if err != nil {
-			return err
-		}
-		value.SetUint(v)
-	case reflect.Float32:
-		v, err := floatx2.Parse32(str)
-		if err != nil {
-			return err
-		}
-		value.SetFloat(float64(v))
-	case reflect.Float64:
-		v, err := floatx2.Parse64(str)
-		if err != nil {
-			return err
-		}
-		value.SetFloat(v)
-	case reflect.Bool:
-		v, err := strconv.ParseBool(str)
-		if err != nil {
-			return err
-		}
-		value.SetBool(v)
+// copy the value. Slices and maps are copied, so the copy is not changed when adding values to the original.
+func copyValue(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	switch {
+	case v.Kind() == reflect.Slice && !v.IsNil():
+		c.Set(reflect.AppendSlice(reflect.MakeSlice(v.Type(), 0, v.Len()), v))
+	case v.Kind() == reflect.Map && !v.IsNil():
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		c.Set(m)
 	default:
-		return fmt.Errorf("unsupported field type: %v", kind)
+		c.Set(v)
 	}
-	return nil
+	return c
 }
@@ -1,7 +1,14 @@
 package flagx
 
 import (
+	"context"
+	"errors"
 	"github.com/hsiafan/glow/timex/durationx"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,3 +55,139 @@ type Option struct {
 	File     string        `flag:"false" index:"0"`
 	Timeout2 time.Duration `flag:"false" index:"1"`
 }
+
+func TestCommand_fallbackValues(t *testing.T) {
+	type FallbackOption struct {
+		Config  string `configfile:"auto"`
+		Host    string `env:"FLAGX_TEST_HOST" default:"localhost"`
+		Port    int    `env:"FLAGX_TEST_PORT" required:"true"`
+		User    string `env:"FLAGX_TEST_USER"`
+		Timeout time.Duration
+		Tags    []string `env:"FLAGX_TEST_TAGS"`
+	}
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte("port = 80\nuser = \"root\"\ntimeout = \"1s\"\n"), 0644))
+	t.Setenv("FLAGX_TEST_PORT", "8080")
+	t.Setenv("FLAGX_TEST_TAGS", "a,b")
+
+	op := &FallbackOption{}
+	cmd, err := NewCommand("my", "", op, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	cmd.ParseAndExecute([]string{"-config", path, "-user", "admin"})
+	assert.Equal(t, "localhost", op.Host)
+	assert.Equal(t, 8080, op.Port)
+	assert.Equal(t, "admin", op.User)
+	assert.Equal(t, durationx.Seconds(1), op.Timeout)
+	assert.Equal(t, []string{"a", "b"}, op.Tags)
+}
+
+func TestCommand_Execute(t *testing.T) {
+	type ExecuteOption struct {
+		Port int    `required:"true"`
+		Name string `flag:"false" index:"0"`
+	}
+
+	var stdout strings.Builder
+	op := &ExecuteOption{}
+	cmd, err := NewContextCommand("my", "", op, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+	cmd.Stdout = &stdout
+
+	err = cmd.Execute([]string{"-unknown"})
+	var unknownFlagErr *UnknownFlagError
+	assert.True(t, errors.As(err, &unknownFlagErr))
+	assert.Equal(t, "unknown", unknownFlagErr.Flag)
+
+	err = cmd.Execute([]string{"-port", "abc"})
+	var invalidValueErr *InvalidValueError
+	assert.True(t, errors.As(err, &invalidValueErr))
+	assert.Equal(t, "Port", invalidValueErr.Field)
+	assert.Equal(t, "abc", invalidValueErr.Value)
+
+	err = cmd.Execute([]string{"n1"})
+	var requiredErr *RequiredArgError
+	assert.True(t, errors.As(err, &requiredErr))
+	assert.Equal(t, "port", requiredErr.Name)
+
+	err = cmd.Execute([]string{"-port", "80", "n1", "n2"})
+	var unhandledErr *UnhandledArgsError
+	assert.True(t, errors.As(err, &unhandledErr))
+	assert.Equal(t, []string{"n2"}, unhandledErr.Args)
+
+	assert.Equal(t, ErrHelp, cmd.Execute([]string{"-h"}))
+	assert.Contains(t, stdout.String(), "Usage: my name")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, cmd.ExecuteContext(ctx, []string{"-port", "80"}))
+}
+
+func TestCommand_executeTwice(t *testing.T) {
+	type Option struct {
+		Port    int      `default:"8080"`
+		Tags    []string `default:"a"`
+		Verbose bool
+		Name    string   `flag:"false" index:"0"`
+		Files   []string `flag:"false"`
+	}
+	op := &Option{}
+	cmd, err := NewCommand("my", "", op, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, cmd.Execute([]string{"-port", "80", "-tags", "b", "-verbose", "n1", "f1"}))
+	assert.Equal(t, Option{Port: 80, Tags: []string{"b"}, Verbose: true, Name: "n1", Files: []string{"f1"}}, *op)
+
+	assert.NoError(t, cmd.Execute([]string{"-port", "81", "-tags", "c"}))
+	assert.Equal(t, Option{Port: 81, Tags: []string{"c"}}, *op)
+
+	assert.NoError(t, cmd.Execute([]string{}))
+	assert.Equal(t, Option{Port: 8080, Tags: []string{"a"}}, *op)
+}
+
+func TestCommand_optionTypes(t *testing.T) {
+	type DBOption struct {
+		Host string `default:"localhost"`
+		Port int
+	}
+	type Common struct {
+		Verbose *bool
+	}
+	type TypesOption struct {
+		Common
+		DB      DBOption  `name:"db"`
+		Backup  *DBOption `name:"bak"`
+		Retries *int
+		Labels  map[string]string `name:"l"`
+		IP      net.IP            `name:"ip"`
+		URL     url.URL           `name:"url"`
+		Since   time.Time         `layout:"2006-01-02"`
+	}
+
+	op := &TypesOption{}
+	cmd, err := NewCommand("my", "", op, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, cmd.Execute([]string{"-verbose", "-db-port", "3306", "-bak-host", "backup", "-l", "a=1",
+		"-l", "b=2", "-ip", "10.0.0.1", "-url", "http://example.com", "-since", "2020-01-02"}))
+	assert.True(t, *op.Verbose)
+	assert.Nil(t, op.Retries)
+	assert.Equal(t, "localhost", op.DB.Host)
+	assert.Equal(t, 3306, op.DB.Port)
+	assert.Equal(t, "backup", op.Backup.Host)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, op.Labels)
+	assert.Equal(t, "10.0.0.1", op.IP.String())
+	assert.Equal(t, "example.com", op.URL.Host)
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), op.Since)
+
+	_, err = NewCommand("my", "", &struct{ C chan int }{}, func() error {
+		return nil
+	})
+	assert.Error(t, err)
+}
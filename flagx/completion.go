@@ -0,0 +1,536 @@
+package flagx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// the hidden command name, for shells to get dynamic completion candidates
+const completeCommandName = "__complete"
+
+// built-in completers, for complete tag
+const (
+	completeFile = "file"
+	completeDir  = "dir"
+)
+
+// supported shells for completion script
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// completer provide completion candidates for a word prefix
+type completer = func(prefix string) []string
+
+// get the completer by complete tag value. The value can be file, dir, or a method name of option,
+// the method should have signature: func(prefix string) []string
+func getCompleter(option reflect.Value, name string) (completer, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case completeFile:
+		return func(prefix string) []string {
+			return completeFiles(prefix, false)
+		}, nil
+	case completeDir:
+		return func(prefix string) []string {
+			return completeFiles(prefix, true)
+		}, nil
+	}
+	method := option.MethodByName(name)
+	if !method.IsValid() {
+		return nil, fmt.Errorf("method %v not found", name)
+	}
+	f, ok := method.Interface().(func(string) []string)
+	if !ok {
+		return nil, fmt.Errorf("method %v should have signature func(string) []string", name)
+	}
+	return f, nil
+}
+
+// list files or dirs start with prefix. dirs are ends with path separator
+func completeFiles(prefix string, dirOnly bool) []string {
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			candidates = append(candidates, match+string(filepath.Separator))
+		} else if !dirOnly {
+			candidates = append(candidates, match)
+		}
+	}
+	return candidates
+}
+
+// print completion candidates for the last argument, one per line
+func printCompletions(w io.Writer, node commandNode, arguments []string) {
+	if len(arguments) == 0 {
+		arguments = []string{""}
+	}
+	for _, candidate := range node.complete(arguments) {
+		_, _ = fmt.Fprintln(w, candidate)
+	}
+}
+
+func (c *Command) complete(arguments []string) []string {
+	c.inheritFlags()
+	fields := c.allFlagFields()
+	syntax := c.syntax()
+	var positionalIndex = 0
+	var flagEnded = false
+	last := len(arguments) - 1
+	for i := 0; i < last; i++ {
+		arg := arguments[i]
+		if !flagEnded && arg == "--" {
+			flagEnded = true
+			continue
+		}
+		if !flagEnded && isFlagArg(arg) {
+			if field := valueNeededField(fields, arg, syntax); field != nil {
+				if i+1 == last {
+					return completeWith(field.completer, arguments[last])
+				}
+				i++
+			}
+			continue
+		}
+		// go flag package stop parsing flags at the first non-flag arg
+		if syntax != SyntaxGNU {
+			flagEnded = true
+		}
+		positionalIndex++
+	}
+
+	cur := arguments[last]
+	if !flagEnded && strings.HasPrefix(cur, "-") {
+		return completeFlag(fields, cur, syntax)
+	}
+	if field, ok := c.positionalFields[positionalIndex]; ok {
+		return completeWith(field.completer, cur)
+	}
+	if c.remainFields != nil {
+		return completeWith(c.remainFields.completer, cur)
+	}
+	return nil
+}
+
+func (c *CompositeCommand) complete(arguments []string) []string {
+	fields := c.allPersistentFields()
+	syntax := c.syntax()
+	last := len(arguments) - 1
+	for i := 0; i < last; i++ {
+		arg := arguments[i]
+		if isFlagArg(arg) {
+			if field := valueNeededField(fields, arg, syntax); field != nil {
+				if i+1 == last {
+					return completeWith(field.completer, arguments[last])
+				}
+				i++
+			}
+			continue
+		}
+		if sc, err := c.findSubCommand(arg); err == nil {
+			return sc.complete(arguments[i+1:])
+		}
+		return nil
+	}
+
+	cur := arguments[last]
+	if strings.HasPrefix(cur, "-") {
+		return completeFlag(fields, cur, syntax)
+	}
+	var candidates []string
+	for _, sc := range c.subCommands {
+		if strings.HasPrefix(sc.nodeName(), cur) {
+			candidates = append(candidates, sc.nodeName())
+		}
+	}
+	return candidates
+}
+
+// complete a flag name, or a flag value in -name=value form
+func completeFlag(fields []*fieldFlagValue, cur string, syntax Syntax) []string {
+	if idx := strings.IndexByte(cur, '='); idx > 0 {
+		field := lookupFlagField(fields, cur[:idx], syntax)
+		if field == nil {
+			return nil
+		}
+		var candidates []string
+		for _, value := range completeWith(field.completer, cur[idx+1:]) {
+			candidates = append(candidates, cur[:idx+1]+value)
+		}
+		return candidates
+	}
+	var forms []string
+	if syntax == SyntaxGNU {
+		for _, field := range fields {
+			forms = append(forms, flagForms(field, syntax)...)
+		}
+	} else {
+		dashes := "-"
+		if strings.HasPrefix(cur, "--") {
+			dashes = "--"
+		}
+		for _, field := range fields {
+			forms = append(forms, dashes+field.name)
+			if field.short != "" {
+				forms = append(forms, dashes+field.short)
+			}
+		}
+	}
+	var candidates []string
+	for _, form := range forms {
+		if strings.HasPrefix(form, cur) {
+			candidates = append(candidates, form)
+		}
+	}
+	return candidates
+}
+
+// the flag forms can be used in command line, such as "-p" and "--port" for GNU syntax
+func flagForms(field *fieldFlagValue, syntax Syntax) []string {
+	var forms []string
+	if syntax == SyntaxGNU {
+		if field.short != "" {
+			forms = append(forms, "-"+field.short)
+		}
+		return append(forms, "--"+field.name)
+	}
+	forms = append(forms, "-"+field.name)
+	if field.short != "" {
+		forms = append(forms, "-"+field.short)
+	}
+	return forms
+}
+
+func completeWith(c completer, prefix string) []string {
+	if c == nil {
+		return nil
+	}
+	var candidates []string
+	for _, candidate := range c(prefix) {
+		if strings.HasPrefix(candidate, prefix) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+func isFlagArg(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-'
+}
+
+// find flag field by arg, the arg is in form -name, --name, or -name=value.
+// For GNU syntax, the arg in -n form is a short flag.
+func lookupFlagField(fields []*fieldFlagValue, arg string, syntax Syntax) *fieldFlagValue {
+	name := strings.TrimLeft(arg, "-")
+	if idx := strings.IndexByte(name, '='); idx >= 0 {
+		name = name[:idx]
+	}
+	if syntax == SyntaxGNU && !strings.HasPrefix(arg, "--") {
+		return findField(fields, name, true)
+	}
+	if field := findField(fields, name, false); field != nil || syntax == SyntaxGNU {
+		return field
+	}
+	return findField(fields, name, true)
+}
+
+// return the flag field which need the next arg as value, or nil
+func valueNeededField(fields []*fieldFlagValue, arg string, syntax Syntax) *fieldFlagValue {
+	if syntax == SyntaxGNU && !strings.HasPrefix(arg, "--") {
+		// combined short flags, such as -vp, the last one may need value
+		for i := 1; i < len(arg); i++ {
+			field := findField(fields, arg[i:i+1], true)
+			if field == nil {
+				return nil
+			}
+			if !field.IsBoolFlag() {
+				if i == len(arg)-1 {
+					return field
+				}
+				return nil
+			}
+		}
+		return nil
+	}
+	field := lookupFlagField(fields, arg, syntax)
+	if field == nil || field.IsBoolFlag() || strings.Contains(arg, "=") {
+		return nil
+	}
+	return field
+}
+
+// GenerateCompletion write the shell completion script for this command to writer.
+// Supported shells are bash, zsh and fish.
+func (c *Command) GenerateCompletion(shell string, w io.Writer) error {
+	return generateCompletion(c, shell, w)
+}
+
+// GenerateCompletion write the shell completion script for this command tree to writer.
+// Supported shells are bash, zsh and fish.
+func (c *CompositeCommand) GenerateCompletion(shell string, w io.Writer) error {
+	return generateCompletion(c, shell, w)
+}
+
+// completion info of one command in command tree
+type completionEntry struct {
+	path     string            // the full command path
+	syntax   Syntax            // the syntax for parsing args
+	commands []commandNode     // sub commands
+	flags    []*fieldFlagValue // all flags can be used
+}
+
+func (e *completionEntry) valueFlags() []*fieldFlagValue {
+	var flags []*fieldFlagValue
+	for _, f := range e.flags {
+		if !f.IsBoolFlag() {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
+func collectCompletionEntries(node commandNode) []*completionEntry {
+	switch n := node.(type) {
+	case *Command:
+		n.inheritFlags()
+		return []*completionEntry{{path: n.path(), syntax: n.syntax(), flags: n.allFlagFields()}}
+	case *CompositeCommand:
+		entries := []*completionEntry{{path: n.path(), syntax: n.syntax(), commands: n.subCommands, flags: n.allPersistentFields()}}
+		for _, sc := range n.subCommands {
+			entries = append(entries, collectCompletionEntries(sc)...)
+		}
+		return entries
+	default:
+		panic(fmt.Sprintf("unknown command node type: %T", node))
+	}
+}
+
+func generateCompletion(node commandNode, shell string, w io.Writer) error {
+	if node.getParent() != nil {
+		return errors.New("completion script can only be generated for root command")
+	}
+	entries := collectCompletionEntries(node)
+	var sb strings.Builder
+	switch shell {
+	case ShellBash:
+		writeBashCompletion(&sb, node.nodeName(), entries)
+	case ShellZsh:
+		writeZshCompletion(&sb, node.nodeName(), entries)
+	case ShellFish:
+		writeFishCompletion(&sb, node.nodeName(), entries)
+	default:
+		return fmt.Errorf("unsupported shell: %v", shell)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// the sub command paths, for shell to determine which command is being completed
+func subCommandPaths(entries []*completionEntry) []string {
+	var paths []string
+	for _, e := range entries[1:] {
+		paths = append(paths, e.path)
+	}
+	return paths
+}
+
+func writeBashCompletion(sb *strings.Builder, name string, entries []*completionEntry) {
+	funcName := "_" + toIdentifier(name) + "_complete"
+	fmt.Fprintf(sb, "# bash completion for %s\n", name)
+	fmt.Fprintf(sb, "%s() {\n", funcName)
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(sb, "    local path=%s i\n", shellQuote(name))
+	if paths := subCommandPaths(entries); len(paths) > 0 {
+		sb.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+		sb.WriteString("        case \"${path} ${COMP_WORDS[i]}\" in\n")
+		fmt.Fprintf(sb, "            %s) path=\"${path} ${COMP_WORDS[i]}\" ;;\n", joinQuoted(paths, "|"))
+		sb.WriteString("        esac\n")
+		sb.WriteString("    done\n")
+	}
+	sb.WriteString("    local commands=\"\" flags=\"\" value_flags=\"\"\n")
+	sb.WriteString("    case \"${path}\" in\n")
+	for _, e := range entries {
+		var commands, flags, valueFlags []string
+		for _, c := range e.commands {
+			commands = append(commands, c.nodeName())
+		}
+		for _, f := range e.flags {
+			flags = append(flags, flagForms(f, e.syntax)...)
+		}
+		for _, f := range e.valueFlags() {
+			valueFlags = append(valueFlags, flagForms(f, e.syntax)...)
+		}
+		fmt.Fprintf(sb, "        %s) commands=%s; flags=%s; value_flags=%s ;;\n", shellQuote(e.path),
+			shellQuote(strings.Join(commands, " ")), shellQuote(strings.Join(flags, " ")),
+			shellQuote(strings.Join(valueFlags, " ")))
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("    if [[ \" ${value_flags} \" == *\" ${prev} \"* || ( \"${cur}\" != -* && -z \"${commands}\" ) ]]; then\n")
+	fmt.Fprintf(sb, "        COMPREPLY=($(compgen -W \"$(%s %s \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null)\" -- \"${cur}\"))\n",
+		shellQuote(name), completeCommandName)
+	sb.WriteString("    elif [[ \"${cur}\" == -* ]]; then\n")
+	sb.WriteString("        COMPREPLY=($(compgen -W \"${flags}\" -- \"${cur}\"))\n")
+	sb.WriteString("    else\n")
+	sb.WriteString("        COMPREPLY=($(compgen -W \"${commands}\" -- \"${cur}\"))\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("}\n")
+	fmt.Fprintf(sb, "complete -o default -F %s %s\n", funcName, shellQuote(name))
+}
+
+func writeZshCompletion(sb *strings.Builder, name string, entries []*completionEntry) {
+	funcName := "_" + toIdentifier(name)
+	fmt.Fprintf(sb, "#compdef %s\n\n", name)
+	fmt.Fprintf(sb, "%s() {\n", funcName)
+	fmt.Fprintf(sb, "    local cmdpath=%s i\n", shellQuote(name))
+	if paths := subCommandPaths(entries); len(paths) > 0 {
+		sb.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+		sb.WriteString("        case \"${cmdpath} ${words[i]}\" in\n")
+		fmt.Fprintf(sb, "            %s) cmdpath=\"${cmdpath} ${words[i]}\" ;;\n", joinQuoted(paths, "|"))
+		sb.WriteString("        esac\n")
+		sb.WriteString("    done\n")
+	}
+	sb.WriteString("    local -a commands flags value_flags values\n")
+	sb.WriteString("    case \"${cmdpath}\" in\n")
+	for _, e := range entries {
+		var commands, flags, valueFlags []string
+		for _, c := range e.commands {
+			commands = append(commands, shellQuote(escapeZshDescribe(c.nodeName())+":"+c.nodeDescription()))
+		}
+		for _, f := range e.flags {
+			for _, form := range flagForms(f, e.syntax) {
+				flags = append(flags, shellQuote(escapeZshDescribe(form)+":"+f.description))
+			}
+		}
+		for _, f := range e.valueFlags() {
+			for _, form := range flagForms(f, e.syntax) {
+				valueFlags = append(valueFlags, shellQuote(form))
+			}
+		}
+		fmt.Fprintf(sb, "        %s) commands=(%s); flags=(%s); value_flags=(%s) ;;\n", shellQuote(e.path),
+			strings.Join(commands, " "), strings.Join(flags, " "), strings.Join(valueFlags, " "))
+	}
+	sb.WriteString("    esac\n")
+	sb.WriteString("    if (( ${value_flags[(Ie)${words[CURRENT-1]}]} )) || [[ \"${words[CURRENT]}\" != -* && ${#commands} -eq 0 ]]; then\n")
+	fmt.Fprintf(sb, "        values=(\"${(@f)$(%s %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\")\n", shellQuote(name), completeCommandName)
+	sb.WriteString("        if [[ -n \"${values[1]}\" ]]; then\n")
+	sb.WriteString("            compadd -a values\n")
+	sb.WriteString("        else\n")
+	sb.WriteString("            _files\n")
+	sb.WriteString("        fi\n")
+	sb.WriteString("    elif [[ \"${words[CURRENT]}\" == -* ]]; then\n")
+	sb.WriteString("        _describe 'flag' flags\n")
+	sb.WriteString("    else\n")
+	sb.WriteString("        _describe 'command' commands\n")
+	sb.WriteString("    fi\n")
+	sb.WriteString("}\n\n")
+	fmt.Fprintf(sb, "compdef %s %s\n", funcName, shellQuote(name))
+}
+
+func writeFishCompletion(sb *strings.Builder, name string, entries []*completionEntry) {
+	funcName := "__" + toIdentifier(name) + "_path"
+	fmt.Fprintf(sb, "# fish completion for %s\n", name)
+	fmt.Fprintf(sb, "function %s\n", funcName)
+	fmt.Fprintf(sb, "    set -l cmdpath %s\n", fishQuote(name))
+	if paths := subCommandPaths(entries); len(paths) > 0 {
+		sb.WriteString("    for word in (commandline -opc)[2..-1]\n")
+		sb.WriteString("        switch \"$cmdpath $word\"\n")
+		var quoted []string
+		for _, path := range paths {
+			quoted = append(quoted, fishQuote(path))
+		}
+		fmt.Fprintf(sb, "            case %s\n", strings.Join(quoted, " "))
+		sb.WriteString("                set cmdpath \"$cmdpath $word\"\n")
+		sb.WriteString("        end\n")
+		sb.WriteString("    end\n")
+	}
+	sb.WriteString("    echo $cmdpath\n")
+	sb.WriteString("end\n\n")
+
+	dynamic := fmt.Sprintf("(%s %s (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)", fishQuote(name), completeCommandName)
+	for _, e := range entries {
+		condition := fishQuote(fmt.Sprintf("test (%s) = %s", funcName, fishQuote(e.path)))
+		for _, c := range e.commands {
+			fmt.Fprintf(sb, "complete -c %s -n %s -f -a %s -d %s\n", fishQuote(name), condition,
+				fishQuote(c.nodeName()), fishQuote(c.nodeDescription()))
+		}
+		for _, f := range e.flags {
+			options := fishFlagOptions(f, e.syntax)
+			if f.IsBoolFlag() {
+				fmt.Fprintf(sb, "complete -c %s -n %s %s -d %s\n", fishQuote(name), condition,
+					options, fishQuote(f.description))
+			} else {
+				fmt.Fprintf(sb, "complete -c %s -n %s %s -r -a %s -d %s\n", fishQuote(name), condition,
+					options, fishQuote(dynamic), fishQuote(f.description))
+			}
+		}
+		if len(e.commands) == 0 {
+			fmt.Fprintf(sb, "complete -c %s -n %s -a %s\n", fishQuote(name), condition, fishQuote(dynamic))
+		}
+	}
+}
+
+// the fish complete options for flag names: -s for short, -l for GNU long, and -o for go style
+func fishFlagOptions(f *fieldFlagValue, syntax Syntax) string {
+	if syntax != SyntaxGNU {
+		options := "-o " + fishQuote(f.name)
+		if f.short != "" {
+			options += " -o " + fishQuote(f.short)
+		}
+		return options
+	}
+	options := "-l " + fishQuote(f.name)
+	if f.short != "" {
+		options = "-s " + fishQuote(f.short) + " " + options
+	}
+	return options
+}
+
+// convert name to a valid shell function identifier
+func toIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// quote str for bash/zsh using single quote
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
+
+func joinQuoted(values []string, sep string) string {
+	var quoted []string
+	for _, value := range values {
+		quoted = append(quoted, shellQuote(value))
+	}
+	return strings.Join(quoted, sep)
+}
+
+// quote str for fish using single quote
+func fishQuote(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	return "'" + strings.ReplaceAll(str, "'", `\'`) + "'"
+}
+
+// escape colon in zsh _describe item names
+func escapeZshDescribe(str string) string {
+	return strings.ReplaceAll(str, ":", `\:`)
+}
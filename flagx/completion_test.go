@@ -0,0 +1,60 @@
+package flagx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type completeOption struct {
+	Host    string `complete:"CompleteHost"`
+	Verbose bool
+	Name    string `flag:"false" index:"0" complete:"CompleteHost"`
+}
+
+func (o *completeOption) CompleteHost(prefix string) []string {
+	return []string{"localhost", "example.com"}
+}
+
+func TestCompositeCommand_complete(t *testing.T) {
+	root := NewCompositeCommand("tool", "the tool")
+	cluster := NewCompositeCommand("cluster", "manage cluster")
+	assert.NoError(t, root.AddCompositeCommand(cluster))
+	assert.NoError(t, cluster.AddSubCommand("add", "add node", &completeOption{}, func() error {
+		return nil
+	}))
+	assert.NoError(t, cluster.AddSubCommand("remove", "remove node", &completeOption{}, func() error {
+		return nil
+	}))
+	assert.Equal(t, []string{"cluster"}, root.complete([]string{"c"}))
+	assert.Equal(t, []string{"add", "remove"}, root.complete([]string{"cluster", ""}))
+	assert.Equal(t, []string{"-host", "-verbose"}, root.complete([]string{"cluster", "add", "-"}))
+	assert.Equal(t, []string{"--host"}, root.complete([]string{"cluster", "add", "--h"}))
+	assert.Equal(t, []string{"localhost"}, root.complete([]string{"cluster", "add", "-host", "l"}))
+	assert.Equal(t, []string{"-host=example.com"}, root.complete([]string{"cluster", "add", "-host=e"}))
+	assert.Equal(t, []string{"example.com"}, root.complete([]string{"cluster", "add", "-verbose", "e"}))
+	assert.Nil(t, root.complete([]string{"cluster", "add", "n1", "e"}))
+}
+
+func TestCompositeCommand_GenerateCompletion(t *testing.T) {
+	root := NewCompositeCommand("tool", "the tool")
+	cluster := NewCompositeCommand("cluster", "manage cluster")
+	assert.NoError(t, root.AddCompositeCommand(cluster))
+	assert.NoError(t, cluster.AddSubCommand("add", "add node", &completeOption{}, func() error {
+		return nil
+	}))
+	for _, shell := range []string{ShellBash, ShellZsh, ShellFish} {
+		var sb strings.Builder
+		assert.NoError(t, root.GenerateCompletion(shell, &sb))
+		assert.Contains(t, sb.String(), "tool cluster add")
+		assert.Contains(t, sb.String(), completeCommandName)
+	}
+	assert.Error(t, root.GenerateCompletion("powershell", &strings.Builder{}))
+}
+
+func Test_getCompleter(t *testing.T) {
+	_, err := getCompleter(reflect.ValueOf(&completeOption{}), "NotExists")
+	assert.Error(t, err)
+}
@@ -1,15 +1,46 @@
 package flagx
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
+// commandNode is a node in command tree, can be a Command or a CompositeCommand
+type commandNode interface {
+	nodeName() string
+	nodeAliases() []string
+	nodeDescription() string
+	getParent() *CompositeCommand
+	setParent(parent *CompositeCommand)
+	printUsage(w io.Writer)
+	stderr() io.Writer
+	// execute the command, return the command node executed finally
+	execute(ctx context.Context, arguments []string) (commandNode, error)
+	// return completion candidates for the last arg
+	complete(arguments []string) []string
+}
+
+var _ commandNode = (*Command)(nil)
+var _ commandNode = (*CompositeCommand)(nil)
+
 // CompositeCommand for commands
 type CompositeCommand struct {
-	Name        string     // the command name
-	Description string     // the description
-	subCommands []*Command // sub commands
+	Name             string            // the command name
+	Aliases          []string          // the alias names of this command, when it is a sub command
+	Description      string            // the description
+	PrefixMatching   bool              // if sub commands can be called by unique prefix of names, such as "st" for "status"
+	Stdout           io.Writer         // for usage and completion output. If not set, use the parent's, or os.Stdout
+	Stderr           io.Writer         // for error output. If not set, use the parent's, or os.Stderr
+	Syntax           Syntax            // the syntax for parsing args. If not set, use the parent's, or SyntaxGo
+	Color            ColorMode         // if usage message is colored. If not set, use the parent's, or color when output is a terminal
+	parent           *CompositeCommand // the parent composite command, if this is a sub command
+	subCommands      []commandNode     // sub commands
+	persistentFields []*fieldFlagValue // persistent flag fields, can be used by all descendant commands
+	persistentGroups []*fieldGroup     // groups of persistent flag fields
 }
 
 // NewCompositeCommand create new CompositeCommand
@@ -27,8 +58,70 @@ func (c *CompositeCommand) AddSubCommand(name string, description string, option
 	if err != nil {
 		return err
 	}
-	command.parentCmd = c.Name
-	c.subCommands = append(c.subCommands, command)
+	return c.AddCommand(command)
+}
+
+// AddContextSubCommand add one sub command, with a handle receive the context passed to ExecuteContext
+func (c *CompositeCommand) AddContextSubCommand(name string, description string, option interface{},
+	handle ContextHandle) error {
+	command, err := NewContextCommand(name, description, option, handle)
+	if err != nil {
+		return err
+	}
+	return c.AddCommand(command)
+}
+
+// AddCommand add one created command as sub command
+func (c *CompositeCommand) AddCommand(command *Command) error {
+	return c.addNode(command)
+}
+
+// AddCompositeCommand add one composite command as sub command, to build a nested command tree, like "tool cluster node add".
+func (c *CompositeCommand) AddCompositeCommand(command *CompositeCommand) error {
+	for p := c; p != nil; p = p.parent {
+		if p == command {
+			return fmt.Errorf("add command %v to %v will make a cycle", command.Name, c.Name)
+		}
+	}
+	return c.addNode(command)
+}
+
+func (c *CompositeCommand) addNode(node commandNode) error {
+	if node.getParent() != nil {
+		return fmt.Errorf("command %v already has parent command %v", node.nodeName(), node.getParent().Name)
+	}
+	for _, sc := range c.subCommands {
+		for _, name := range nodeNames(node) {
+			if matchNodeName(sc, name) {
+				return fmt.Errorf("command %v already has sub command %v", c.Name, name)
+			}
+		}
+	}
+	node.setParent(c)
+	c.subCommands = append(c.subCommands, node)
+	return nil
+}
+
+// AddPersistentFlags add flag args defined by option struct. The persistent flags can be set in this command
+// and all descendant commands, and the option values can be read by handles of all descendant commands.
+// The option struct can only contain flag args.
+func (c *CompositeCommand) AddPersistentFlags(option interface{}) error {
+	fields, err := parseOptionFields(option)
+	if err != nil {
+		return err
+	}
+	if len(fields.positionalFields) > 0 || fields.remainFields != nil {
+		return errors.New("persistent flags option can not contains non-flag args")
+	}
+	for _, field := range fields.flagFields {
+		for _, pf := range c.persistentFields {
+			if pf.name == field.name {
+				return fmt.Errorf("persistent flag %v already defined", field.name)
+			}
+		}
+		c.persistentFields = append(c.persistentFields, field)
+	}
+	c.persistentGroups = append(c.persistentGroups, fields.groups...)
 	return nil
 }
 
@@ -37,33 +130,229 @@ func (c *CompositeCommand) ParseOsArgsAndExecute() {
 	c.ParseAndExecute(os.Args[1:])
 }
 
-// ParseAndExecute parse arguments, and execute command
+// ParseAndExecute parse arguments, and execute command. If error occurred, will exit with non-zero code.
 func (c *CompositeCommand) ParseAndExecute(arguments []string) {
-	if len(arguments) == 0 {
-		arguments = []string{"help"}
+	node, err := c.execute(context.Background(), arguments)
+	if err == nil || err == ErrHelp {
+		return
+	}
+	_, _ = fmt.Fprintln(node.stderr(), err)
+	if _, ok := err.(*UnknownCommandError); !ok {
+		node.printUsage(node.stderr())
+	}
+	os.Exit(-1)
+}
+
+// Execute parse arguments, and execute the sub command. Errors are returned instead of exiting the process.
+// If the sub command is not found, an *UnknownCommandError is returned.
+// If help is requested, the usage message is shown, and ErrHelp is returned.
+func (c *CompositeCommand) Execute(arguments []string) error {
+	return c.ExecuteContext(context.Background(), arguments)
+}
+
+// ExecuteContext is the same as Execute, and the ctx is passed to sub command handle.
+func (c *CompositeCommand) ExecuteContext(ctx context.Context, arguments []string) error {
+	_, err := c.execute(ctx, arguments)
+	return err
+}
+
+func (c *CompositeCommand) execute(ctx context.Context, arguments []string) (commandNode, error) {
+	if c.parent == nil && len(arguments) > 0 && arguments[0] == completeCommandName {
+		printCompletions(c.stdout(), c, arguments[1:])
+		return c, nil
+	}
+	for _, f := range c.persistentFields {
+		f.reset()
 	}
-	if len(arguments) == 1 && (arguments[0] == "help" || arguments[0] == "-h" || arguments[0] == "-help") {
+	// persistent flags before the sub command name
+	arguments, err := parseArgs(arguments, c.allPersistentFields(), c.syntax(), false)
+	if err == ErrHelp {
 		c.ShowUsage()
-		return
+		return c, ErrHelp
 	}
+	if err != nil {
+		return c, err
+	}
+
+	if len(arguments) == 0 || len(arguments) == 1 && arguments[0] == "help" {
+		c.ShowUsage()
+		return c, ErrHelp
+	}
+	sc, err := c.findSubCommand(arguments[0])
+	if err != nil {
+		return c, err
+	}
+	return sc.execute(ctx, arguments[1:])
+}
+
+// find sub command by name, alias, or unique prefix if PrefixMatching is true.
+// If not found, return *UnknownCommandError with suggestions.
+func (c *CompositeCommand) findSubCommand(name string) (commandNode, error) {
 	for _, sc := range c.subCommands {
-		if sc.Name == arguments[0] {
-			sc.ParseAndExecute(arguments[1:])
-			return
+		if matchNodeName(sc, name) {
+			return sc, nil
 		}
 	}
-	fmt.Println("unknown command: " + arguments[0])
-	os.Exit(-1)
+
+	var names []string
+	for _, sc := range c.subCommands {
+		names = append(names, nodeNames(sc)...)
+	}
+	if c.PrefixMatching && name != "" {
+		var matched []commandNode
+		var matchedNames []string
+		for _, sc := range c.subCommands {
+			for _, n := range nodeNames(sc) {
+				if strings.HasPrefix(n, name) {
+					matched = append(matched, sc)
+					matchedNames = append(matchedNames, n)
+					break
+				}
+			}
+		}
+		if len(matched) == 1 {
+			return matched[0], nil
+		}
+		if len(matched) > 1 {
+			return nil, &UnknownCommandError{Command: name, Suggestions: matchedNames}
+		}
+	}
+	return nil, &UnknownCommandError{Command: name, Suggestions: suggest(name, names)}
+}
+
+// the name and aliases of command node
+func nodeNames(node commandNode) []string {
+	return append([]string{node.nodeName()}, node.nodeAliases()...)
+}
+
+// if the name is the name or an alias of command node
+func matchNodeName(node commandNode, name string) bool {
+	for _, n := range nodeNames(node) {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 // ShowUsage show usage
 func (c *CompositeCommand) ShowUsage() {
+	c.printUsage(c.stdout())
+}
+
+func (c *CompositeCommand) printUsage(w io.Writer) {
+	uw := newUsageWriter(w, c.colorMode())
 	if c.Description != "" {
-		fmt.Println(c.Description + "\n")
+		uw.paragraph(c.Description)
 	}
-	fmt.Println("Usage:", c.Name)
+	uw.synopsis(c.path(), "command")
+
+	var commandRows []usageRow
 	for _, command := range c.subCommands {
-		fmt.Println("  ", command.Name)
-		fmt.Println("    ", command.Description)
+		name := strings.Join(nodeNames(command), ", ")
+		commandRows = append(commandRows, usageRow{name: name, desc: command.nodeDescription()})
 	}
+	uw.section("Commands", commandRows)
+
+	uw.flagSections("Flags", c.persistentFields, c.syntax())
+	uw.flagSections("Global Flags", c.inheritedFields(), c.syntax())
+}
+
+func (c *CompositeCommand) stdout() io.Writer {
+	if c.Stdout != nil {
+		return c.Stdout
+	}
+	if c.parent != nil {
+		return c.parent.stdout()
+	}
+	return os.Stdout
+}
+
+func (c *CompositeCommand) stderr() io.Writer {
+	if c.Stderr != nil {
+		return c.Stderr
+	}
+	if c.parent != nil {
+		return c.parent.stderr()
+	}
+	return os.Stderr
+}
+
+// the color mode for usage message
+func (c *CompositeCommand) colorMode() ColorMode {
+	if c.Color == ColorAuto && c.parent != nil {
+		return c.parent.colorMode()
+	}
+	return c.Color
+}
+
+// the syntax for parsing args
+func (c *CompositeCommand) syntax() Syntax {
+	if c.Syntax != SyntaxDefault {
+		return c.Syntax
+	}
+	if c.parent != nil {
+		return c.parent.syntax()
+	}
+	return SyntaxGo
+}
+
+// persistent flag fields of ancestors, not shadowed by persistent flags of this command
+func (c *CompositeCommand) inheritedFields() []*fieldFlagValue {
+	if c.parent == nil {
+		return nil
+	}
+	var fields []*fieldFlagValue
+	for _, f := range c.parent.allPersistentFields() {
+		if findField(c.persistentFields, f.name, false) == nil && findField(fields, f.name, false) == nil {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// persistent flag fields of this command and all ancestors. Fields of nearer command come first.
+func (c *CompositeCommand) allPersistentFields() []*fieldFlagValue {
+	var fields []*fieldFlagValue
+	for p := c; p != nil; p = p.parent {
+		fields = append(fields, p.persistentFields...)
+	}
+	return fields
+}
+
+// persistent flag groups of this command and all ancestors
+func (c *CompositeCommand) allPersistentGroups() []*fieldGroup {
+	var groups []*fieldGroup
+	for p := c; p != nil; p = p.parent {
+		groups = append(groups, p.persistentGroups...)
+	}
+	return groups
+}
+
+// path return the full command path, from the root command
+func (c *CompositeCommand) path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.path() + " " + c.Name
+}
+
+func (c *CompositeCommand) nodeName() string {
+	return c.Name
+}
+
+func (c *CompositeCommand) nodeAliases() []string {
+	return c.Aliases
+}
+
+func (c *CompositeCommand) nodeDescription() string {
+	return c.Description
+}
+
+func (c *CompositeCommand) getParent() *CompositeCommand {
+	return c.parent
+}
+
+func (c *CompositeCommand) setParent(parent *CompositeCommand) {
+	c.parent = parent
 }
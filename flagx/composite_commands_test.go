@@ -1,7 +1,9 @@
 package flagx
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -17,3 +19,59 @@ func TestCompositeCommand_Parse(t *testing.T) {
 	ccmd.ParseAndExecute([]string{"my", "-update", "f0", "0s", "f1"})
 	//err = ccmd.ParseAndExecute([]string{"help"})
 }
+
+func TestCompositeCommand_nested(t *testing.T) {
+	type GlobalOption struct {
+		Verbose bool
+		Cluster string `default:"default"`
+	}
+	type AddOption struct {
+		Port int
+		Name string `flag:"false" index:"0"`
+	}
+
+	gop := &GlobalOption{}
+	op := &AddOption{}
+	var executed bool
+
+	root := NewCompositeCommand("tool", "the tool")
+	assert.NoError(t, root.AddPersistentFlags(gop))
+	cluster := NewCompositeCommand("cluster", "manage cluster")
+	node := NewCompositeCommand("node", "manage node")
+	assert.NoError(t, root.AddCompositeCommand(cluster))
+	assert.NoError(t, cluster.AddCompositeCommand(node))
+	assert.Error(t, node.AddCompositeCommand(root))
+	assert.Error(t, root.AddCompositeCommand(cluster))
+	assert.NoError(t, node.AddSubCommand("add", "add node", op, func() error {
+		executed = true
+		return nil
+	}))
+	assert.Equal(t, "tool cluster node add", node.subCommands[0].(*Command).path())
+
+	root.ParseAndExecute([]string{"-verbose", "cluster", "node", "add", "-port", "80", "-cluster", "c1", "n1"})
+	assert.True(t, executed)
+	assert.True(t, gop.Verbose)
+	assert.Equal(t, "c1", gop.Cluster)
+	assert.Equal(t, 80, op.Port)
+	assert.Equal(t, "n1", op.Name)
+}
+
+func TestCompositeCommand_Execute(t *testing.T) {
+	var stdout strings.Builder
+	ccmd := NewCompositeCommand("your", "composite command")
+	ccmd.Stdout = &stdout
+	assert.NoError(t, ccmd.AddSubCommand("my", "a test cmd", &Option{}, func() error {
+		return nil
+	}))
+
+	err := ccmd.Execute([]string{"other"})
+	var unknownCommandErr *UnknownCommandError
+	assert.True(t, errors.As(err, &unknownCommandErr))
+	assert.Equal(t, "other", unknownCommandErr.Command)
+
+	assert.Equal(t, ErrHelp, ccmd.Execute([]string{"help"}))
+	assert.Contains(t, stdout.String(), "Usage: your")
+	stdout.Reset()
+	assert.Equal(t, ErrHelp, ccmd.Execute([]string{"my", "-h"}))
+	assert.Contains(t, stdout.String(), "Usage: your my")
+}
@@ -0,0 +1,234 @@
+package flagx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config file formats
+const (
+	configFormatAuto = "auto"
+	configFormatJSON = "json"
+	configFormatTOML = "toml"
+	configFormatINI  = "ini"
+)
+
+// configValues hold values loaded from config file. the key is flag name, nested keys(json object, toml/ini section)
+// are joined with '-'. One key may have multi values, for slice fields.
+type configValues map[string][]string
+
+// loadConfigFile load config values from file. If format is empty or auto, use the file extension to determine format.
+func loadConfigFile(path string, format string) (configValues, error) {
+	if format == "" || format == configFormatAuto {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case configFormatJSON:
+		return parseJSONConfig(data)
+	case configFormatTOML:
+		return parseTOMLConfig(string(data))
+	case configFormatINI, "cfg", "conf":
+		return parseINIConfig(string(data))
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %v", format)
+	}
+}
+
+// parse json config. The top level should be a json object.
+func parseJSONConfig(data []byte) (configValues, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	values := configValues{}
+	if err := values.addJSONObject("", m); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (cv configValues) addJSONObject(prefix string, m map[string]interface{}) error {
+	for key, value := range m {
+		name := joinConfigKey(prefix, key)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := cv.addJSONObject(name, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, item := range v {
+				s, err := jsonScalarToString(item)
+				if err != nil {
+					return fmt.Errorf("invalid config value for %v: %w", name, err)
+				}
+				cv[name] = append(cv[name], s)
+			}
+		default:
+			s, err := jsonScalarToString(v)
+			if err != nil {
+				return fmt.Errorf("invalid config value for %v: %w", name, err)
+			}
+			cv[name] = append(cv[name], s)
+		}
+	}
+	return nil
+}
+
+func jsonScalarToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("not a scalar value: %v", value)
+	}
+}
+
+// parse a toml config. Only a subset of toml is supported: tables, key/value pairs with string, number, bool values,
+// and single line arrays of these values.
+func parseTOMLConfig(content string) (configValues, error) {
+	values := configValues{}
+	var section string
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripComment(line, "#"))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("invalid toml table at line %v: %v", i+1, line)
+			}
+			section = strings.Trim(line, "[] ")
+			section = strings.ReplaceAll(section, ".", "-")
+			continue
+		}
+		idx := strings.IndexByte(line, '=')
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid toml line %v: %v", i+1, line)
+		}
+		key := unquote(strings.TrimSpace(line[:idx]))
+		name := joinConfigKey(section, key)
+		value := strings.TrimSpace(line[idx+1:])
+		if strings.HasPrefix(value, "[") {
+			if !strings.HasSuffix(value, "]") {
+				return nil, fmt.Errorf("invalid toml array at line %v: %v", i+1, line)
+			}
+			for _, item := range splitTOMLArray(value[1 : len(value)-1]) {
+				values[name] = append(values[name], unquote(item))
+			}
+			continue
+		}
+		values[name] = append(values[name], unquote(value))
+	}
+	return values, nil
+}
+
+// split toml array items, separated by comma. Commas in quoted strings are kept.
+func splitTOMLArray(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = appendNonEmpty(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return appendNonEmpty(items, s[start:])
+}
+
+// parse an ini config. Keys in sections are prefixed with section name. repeated keys are treated as multi values.
+func parseINIConfig(content string) (configValues, error) {
+	values := configValues{}
+	var section string
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("invalid ini section at line %v: %v", i+1, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid ini line %v: %v", i+1, line)
+		}
+		name := joinConfigKey(section, strings.TrimSpace(line[:idx]))
+		values[name] = append(values[name], unquote(strings.TrimSpace(line[idx+1:])))
+	}
+	return values, nil
+}
+
+func joinConfigKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "-" + key
+}
+
+// strip the comment start with marker, markers in quoted strings are kept.
+func stripComment(line string, marker string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(line[i:], marker):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// remove the surrounding quotes of a string value
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		if s[0] == '"' && s[len(s)-1] == '"' {
+			if v, err := strconv.Unquote(s); err == nil {
+				return v
+			}
+			return s[1 : len(s)-1]
+		}
+		if s[0] == '\'' && s[len(s)-1] == '\'' {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+func appendNonEmpty(items []string, item string) []string {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return items
+	}
+	return append(items, item)
+}
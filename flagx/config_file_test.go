@@ -0,0 +1,54 @@
+package flagx
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_parseJSONConfig(t *testing.T) {
+	values, err := parseJSONConfig([]byte(`{"name": "kite", "age": 10, "dry": false, "tags": [1, 2], "db": {"host": "h"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, configValues{
+		"name":    {"kite"},
+		"age":     {"10"},
+		"dry":     {"false"},
+		"tags":    {"1", "2"},
+		"db-host": {"h"},
+	}, values)
+}
+
+func Test_parseTOMLConfig(t *testing.T) {
+	values, err := parseTOMLConfig(`
+# comment
+name = "kite # not comment" # comment
+age = 10
+tags = [1, 2]
+
+[db]
+host = 'h'
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, configValues{
+		"name":    {"kite # not comment"},
+		"age":     {"10"},
+		"tags":    {"1", "2"},
+		"db-host": {"h"},
+	}, values)
+}
+
+func Test_parseINIConfig(t *testing.T) {
+	values, err := parseINIConfig(`
+; comment
+name = kite
+tags = 1
+tags = 2
+[db]
+host: h
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, configValues{
+		"name":    {"kite"},
+		"tags":    {"1", "2"},
+		"db-host": {"h"},
+	}, values)
+}
@@ -22,6 +22,11 @@ Usage:
 	}
 	cmd.ParseOsArgsAndExecute()
 
+ParseAndExecute exits the process if error occurred. To handle errors, such as in tests, use Execute/ExecuteContext,
+which return typed errors: *UnknownFlagError, *UnknownCommandError, *InvalidValueError, *UnhandledArgsError,
+*ValidationError, or ErrHelp if usage is shown. The output writers can be set by Stdout and Stderr fields.
+The handle can receive the context passed to ExecuteContext, by using NewContextCommand or AddContextSubCommand.
+
 3. For composite command line:
 
 	cc := flagx.NewCompositeCommand("my_cc", "some description")
@@ -31,14 +36,81 @@ Usage:
 	})
 	cc.ParseAndExecute(os.Args[1:])
 
+Composite commands can be nested, to build a command tree like "tool cluster node add".
+Persistent flags added to a composite command can be set in it and all its descendant commands:
+
+	root := flagx.NewCompositeCommand("tool", "some description")
+	globalOption := &GlobalOption{}
+	_ = root.AddPersistentFlags(globalOption)
+	cluster := flagx.NewCompositeCommand("cluster", "some description")
+	_ = root.AddCompositeCommand(cluster)
+	_ = cluster.AddSubCommand("add", "some description", option, func() error {
+		return myHandle(globalOption, option)
+	})
+	root.ParseAndExecute(os.Args[1:])
+
 4. struct field tag:
 	name:			The arg name. if not set, use converted struct filed name
+	short:			The one char short alias of flag name, such as "p" for "port"
 	default:		Default arg value
 	description:	Arg usage and other messages
 	"flag"          If is a flag arg(true) or a non-flag arg(false), default is true.
 	index:			The position of non-flag args, start from 0. If a field with slice type, set args true, and leave index not set, will receive all non-consumed non-flag args.
 	ignore:			Ignore this field, do not parse and add arg flag
 	"required"      If this args is required(true|false). Default is false. If default value is set, the required filed will be ignored.
+	env:			The environment variable name. If the flag arg is not set in command line, use the env value. For slice type, the env value is split by comma.
+	configfile:		Mark a string flag arg as config file path. The tag value is the config format: json, toml, ini, or auto(determined by file extension).
+				Keys in config file are flag names; keys in nested json objects and toml/ini sections are joined with '-', such as "db-host".
+	layout:			The layout for parsing time.Time value.
+	choices:		The allowed values, separated by comma, such as "json,yaml"
+	min:			The min value, for numeric and time.Duration type
+	max:			The max value, for numeric and time.Duration type
+	pattern:		The regexp pattern the value should match
+	exists:			The value should be a path of an existing file or dir: file|dir
+	exclusive:		The names of mutually exclusive groups, separated by comma. At most one flag arg in a group can be set
+	together:		The names of groups, separated by comma. The flag args in a group should be all set, or all not set
+	group:			The group name for showing the flag arg in usage message, such as "Network"
+	complete:		The shell completion for arg value: file, dir, or a method name of the option struct, with signature func(prefix string) []string.
+
+Flag arg values are taken in this order: command line flag > env > config file > default.
+
+The required, validation and group constraints are checked after all args are parsed, and all violations are reported
+at once by a *ValidationError, which contains *RequiredArgError, *InvalidValueError and *GroupError.
+
+Sub commands can have alias names, set by Aliases field. If PrefixMatching of composite command is true, sub commands
+can be called by unique prefix of names, such as "st" for "status". For mistyped sub command and flag names,
+*UnknownCommandError and *UnknownFlagError contain similar names as suggestions, shown as "did you mean 'status'?".
+
+Command line syntax:
+
+By default, args are parsed by go flag package syntax: flags are in -name or --name form, and all flag args should be
+before non-flag args. Set Syntax field of command to SyntaxGNU, to use POSIX/GNU style syntax:
+
+	cmd.Syntax = flagx.SyntaxGNU
+
+Then long flags are in --name form, short flags are in -p form and can be combined like -vx or -p80, bool flags can be
+negated like --no-color, flag args and non-flag args can be interspersed, and "--" terminates flag args.
+Sub commands use the syntax of parent composite command, if their Syntax field is not set.
+
+Shell completion:
+
+Use GenerateCompletion to write completion script for bash, zsh or fish. The scripts call the hidden "__complete"
+sub command of the root command, to get dynamic completions for arg values at runtime:
+
+	_ = cc.GenerateCompletion(flagx.ShellBash, os.Stdout)
+
+Usage message:
+
+The usage message is wrapped by terminal width, and flag args are shown in aligned columns, grouped by group tag.
+It is colored by ANSI escape codes if output is a terminal and NO_COLOR env is not set; set Color field of command
+to ColorAlways or ColorNever to change this.
+
+Docs:
+
+Use GenerateDocs to write Markdown pages or roff man pages to a dir, one file per command, such as in a go generate step:
+
+	_ = cc.GenerateDocs(flagx.DocMarkdown, "docs")
+	_ = cc.GenerateDocs(flagx.DocMan, "man")
 
 5. supported struct field type:
 	string
@@ -56,9 +128,18 @@ Usage:
 	float32
 	float64
 	time.Duration
+	time.Time		Parsed with the layout tag, or RFC3339 if layout tag not set
+	url.URL
+	types implement flag.Value or encoding.TextUnmarshaler(by pointer), such as net.IP
+
+Pointer to the types above can be used, to distinguish not set from zero value.
+Slice type with the element type above can be used, flag args can be set multi times, and stored in the slice, such as "-f 1 -f 2".
+Map type with the key and value type above can be used, flag args are in key=value form, such as "-l k1=v1 -l k2=v2".
+For slice and map types, the default and env value are split by comma.
+However, positional non-flag args can not use slices and map type.
 
-Pointer and are not supported.
-And, slice type with the element type above can be used, flag args can be set multi times, and stored in the slice, such as "-f 1 -f 2".
-However, positional non-flag args can not use slices type.
+Struct type fields(or pointer to struct) are nested options, the flag names of the nested fields are prefixed with
+the flag name of the struct field, such as "-db-host". For embedded struct without name tag, no prefix is added.
+Nested options can only contain flag args.
 */
 package flagx
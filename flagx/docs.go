@@ -0,0 +1,348 @@
+package flagx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/hsiafan/glow/stringx"
+)
+
+// Doc formats supported by GenerateDocs
+const (
+	DocMarkdown = "markdown" // markdown pages, with .md extension
+	DocMan      = "man"      // roff man pages, in section 1
+)
+
+const manSection = "1"
+
+// GenerateDocs write doc files for this command to dir, in format DocMarkdown or DocMan.
+func (c *Command) GenerateDocs(format string, dir string) error {
+	return generateDocs(c, format, dir)
+}
+
+// GenerateDocs write doc files for this command and all descendant commands to dir, one file per command,
+// in format DocMarkdown or DocMan.
+func (c *CompositeCommand) GenerateDocs(format string, dir string) error {
+	return generateDocs(c, format, dir)
+}
+
+// doc info of one command in command tree
+type docEntry struct {
+	name        string                // the full command path
+	description string                // the command description
+	parent      *docEntry             // the parent composite command
+	commands    []*docEntry           // sub commands
+	syntax      Syntax                // the syntax for parsing args
+	flags       []*fieldFlagValue     // flags defined by this command
+	inherited   []*fieldFlagValue     // persistent flags inherited from ancestors
+	positional  []*positionalArgField // positional non-flag args, by index
+	remain      *remainedArgsField    // remained non-flag args, may be nil
+}
+
+// the file name for doc, such as "tool-run.md"
+func (e *docEntry) fileName(format string) string {
+	name := strings.ReplaceAll(e.name, " ", "-")
+	if format == DocMan {
+		return name + "." + manSection
+	}
+	return name + ".md"
+}
+
+// the args part of synopsis, such as "[flags] name [files...]"
+func (e *docEntry) synopsisArgs() string {
+	var parts []string
+	if len(e.flags) > 0 || len(e.inherited) > 0 {
+		parts = append(parts, "[flags]")
+	}
+	if len(e.commands) > 0 {
+		parts = append(parts, "command")
+	}
+	for _, f := range e.positional {
+		if f.required {
+			parts = append(parts, f.name)
+		} else {
+			parts = append(parts, "["+f.name+"]")
+		}
+	}
+	if e.remain != nil {
+		parts = append(parts, "["+e.remain.name+"...]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func collectDocEntries(node commandNode, parent *docEntry) []*docEntry {
+	var entry *docEntry
+	switch n := node.(type) {
+	case *Command:
+		n.inheritFlags()
+		entry = &docEntry{name: n.path(), description: n.Description, parent: parent, syntax: n.syntax(),
+			flags: n.flagFields, inherited: n.inherited, remain: n.remainFields}
+		for idx := 0; idx < len(n.positionalFields); idx++ {
+			entry.positional = append(entry.positional, n.positionalFields[idx])
+		}
+		return []*docEntry{entry}
+	case *CompositeCommand:
+		entry = &docEntry{name: n.path(), description: n.Description, parent: parent, syntax: n.syntax(),
+			flags: n.persistentFields, inherited: n.inheritedFields()}
+		entries := []*docEntry{entry}
+		for _, sc := range n.subCommands {
+			subEntries := collectDocEntries(sc, entry)
+			entry.commands = append(entry.commands, subEntries[0])
+			entries = append(entries, subEntries...)
+		}
+		return entries
+	default:
+		panic(fmt.Sprintf("unknown command node type: %T", node))
+	}
+}
+
+func generateDocs(node commandNode, format string, dir string) error {
+	var write func(w io.Writer, e *docEntry) error
+	switch format {
+	case DocMarkdown:
+		write = writeMarkdownDoc
+	case DocMan:
+		write = writeManDoc
+	default:
+		return fmt.Errorf("unsupported doc format: %v", format)
+	}
+	if node.getParent() != nil {
+		return errors.New("docs can only be generated for root command")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, e := range collectDocEntries(node, nil) {
+		if err := writeDocFile(filepath.Join(dir, e.fileName(format)), e, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDocFile(path string, e *docEntry, write func(w io.Writer, e *docEntry) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, e); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write doc file %v error: %w", path, err)
+	}
+	return f.Close()
+}
+
+// the attributes of flag shown in doc, such as "default: 80", "env: $PORT", "required"
+func flagDocAttrs(f *fieldFlagValue) []string {
+	var attrs []string
+	if f.hasDefault && f.defaultValue != "" {
+		attrs = append(attrs, "default: "+f.defaultValue)
+	}
+	if f.env != "" {
+		attrs = append(attrs, "env: $"+f.env)
+	}
+	if f.required && !f.hasDefault {
+		attrs = append(attrs, "required")
+	}
+	return attrs
+}
+
+func writeMarkdownDoc(w io.Writer, e *docEntry) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", e.name)
+	if e.description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", e.description)
+	}
+	sb.WriteString("## Synopsis\n\n```\n")
+	sb.WriteString(e.name + stringx.PrependIfNotEmpty(e.synopsisArgs(), " "))
+	sb.WriteString("\n```\n\n")
+
+	if len(e.positional) > 0 || e.remain != nil {
+		sb.WriteString("## Arguments\n\n")
+		sb.WriteString("| Name | Type | Required | Description |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range e.positional {
+			required := ""
+			if f.required {
+				required = "yes"
+			}
+			fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n", f.name, docTypeName(f._type), required,
+				escapeMarkdownCell(f.description))
+		}
+		if f := e.remain; f != nil {
+			fmt.Fprintf(&sb, "| `%s...` | %s |  | %s |\n", f.name, docTypeName(f._type),
+				escapeMarkdownCell(f.description))
+		}
+		sb.WriteString("\n")
+	}
+
+	writeMarkdownFlags(&sb, "Flags", e.flags, e.syntax)
+	writeMarkdownFlags(&sb, "Global Flags", e.inherited, e.syntax)
+
+	if len(e.commands) > 0 {
+		sb.WriteString("## Commands\n\n")
+		for _, sc := range e.commands {
+			fmt.Fprintf(&sb, "* [%s](%s) - %s\n", sc.name, sc.fileName(DocMarkdown), firstLine(sc.description))
+		}
+		sb.WriteString("\n")
+	}
+	if p := e.parent; p != nil {
+		sb.WriteString("## See Also\n\n")
+		fmt.Fprintf(&sb, "* [%s](%s) - %s\n", p.name, p.fileName(DocMarkdown), firstLine(p.description))
+	}
+	_, err := io.WriteString(w, strings.TrimRight(sb.String(), "\n")+"\n")
+	return err
+}
+
+func writeMarkdownFlags(sb *strings.Builder, title string, fields []*fieldFlagValue, syntax Syntax) {
+	if len(fields) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "## %s\n\n", title)
+	sb.WriteString("| Flag | Type | Default | Env | Required | Description |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, f := range fields {
+		var defaultValue, env, required string
+		if f.hasDefault && f.defaultValue != "" {
+			defaultValue = "`" + f.defaultValue + "`"
+		}
+		if f.env != "" {
+			env = "`$" + f.env + "`"
+		}
+		if f.required && !f.hasDefault {
+			required = "yes"
+		}
+		fmt.Fprintf(sb, "| `%s` | %s | %s | %s | %s | %s |\n", flagDisplayName(f, syntax), docTypeName(f._type),
+			defaultValue, env, required, escapeMarkdownCell(f.description))
+	}
+	sb.WriteString("\n")
+}
+
+func writeManDoc(w io.Writer, e *docEntry) error {
+	var sb strings.Builder
+	title := strings.ToUpper(strings.ReplaceAll(e.name, " ", "-"))
+	fmt.Fprintf(&sb, ".TH \"%s\" \"%s\"\n", title, manSection)
+	sb.WriteString(".SH NAME\n")
+	sb.WriteString(escapeRoff(strings.ReplaceAll(e.name, " ", "-")))
+	if desc := firstLine(e.description); desc != "" {
+		sb.WriteString(" \\- " + escapeRoff(desc))
+	}
+	sb.WriteString("\n.SH SYNOPSIS\n")
+	fmt.Fprintf(&sb, ".B %s\n", escapeRoff(e.name))
+	if args := e.synopsisArgs(); args != "" {
+		sb.WriteString(escapeRoffLine(args) + "\n")
+	}
+	if e.description != "" {
+		sb.WriteString(".SH DESCRIPTION\n")
+		sb.WriteString(escapeRoffLine(e.description) + "\n")
+	}
+
+	if len(e.positional) > 0 || e.remain != nil {
+		sb.WriteString(".SH ARGUMENTS\n")
+		for _, f := range e.positional {
+			fmt.Fprintf(&sb, ".TP\n.B %s\n", escapeRoff(f.name))
+			desc := f.description
+			if f.required {
+				desc = strings.TrimSpace(desc + " (required)")
+			}
+			sb.WriteString(escapeRoffLine(desc) + "\n")
+		}
+		if f := e.remain; f != nil {
+			fmt.Fprintf(&sb, ".TP\n.B %s...\n", escapeRoff(f.name))
+			sb.WriteString(escapeRoffLine(f.description) + "\n")
+		}
+	}
+
+	writeManFlags(&sb, "OPTIONS", e.flags, e.syntax)
+	writeManFlags(&sb, "GLOBAL OPTIONS", e.inherited, e.syntax)
+
+	if len(e.commands) > 0 {
+		sb.WriteString(".SH COMMANDS\n")
+		for _, sc := range e.commands {
+			fmt.Fprintf(&sb, ".TP\n.B %s\n", escapeRoff(sc.name[strings.LastIndexByte(sc.name, ' ')+1:]))
+			sb.WriteString(escapeRoffLine(firstLine(sc.description)) + "\n")
+		}
+	}
+
+	var related []string
+	if e.parent != nil {
+		related = append(related, e.parent.name)
+	}
+	for _, sc := range e.commands {
+		related = append(related, sc.name)
+	}
+	if len(related) > 0 {
+		sb.WriteString(".SH SEE ALSO\n")
+		for i, name := range related {
+			sep := ","
+			if i == len(related)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&sb, ".BR %s (%s)%s\n", escapeRoff(strings.ReplaceAll(name, " ", "-")), manSection, sep)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeManFlags(sb *strings.Builder, title string, fields []*fieldFlagValue, syntax Syntax) {
+	if len(fields) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, ".SH %s\n", title)
+	for _, f := range fields {
+		sb.WriteString(".TP\n")
+		fmt.Fprintf(sb, "\\fB%s\\fR", escapeRoff(flagDisplayName(f, syntax)))
+		if typeName := valueTypeName(f._type); typeName != "" {
+			fmt.Fprintf(sb, " \\fI%s\\fR", escapeRoff(typeName))
+		}
+		sb.WriteString("\n")
+		desc := f.description
+		if attrs := flagDocAttrs(f); len(attrs) > 0 {
+			desc = strings.TrimSpace(desc + " (" + strings.Join(attrs, ", ") + ")")
+		}
+		sb.WriteString(escapeRoffLine(desc) + "\n")
+	}
+}
+
+// the value type name shown in markdown doc
+func docTypeName(t reflect.Type) string {
+	if isBoolType(t) {
+		return "bool"
+	}
+	return valueTypeName(t)
+}
+
+func firstLine(str string) string {
+	if idx := strings.IndexByte(str, '\n'); idx >= 0 {
+		return str[:idx]
+	}
+	return str
+}
+
+func escapeMarkdownCell(str string) string {
+	str = strings.ReplaceAll(str, "|", "\\|")
+	return strings.ReplaceAll(str, "\n", "<br>")
+}
+
+// escape text for roff
+func escapeRoff(str string) string {
+	str = strings.ReplaceAll(str, "\\", "\\e")
+	return strings.ReplaceAll(str, "-", "\\-")
+}
+
+// escape text for roff, and lines start with control chars
+func escapeRoffLine(str string) string {
+	lines := strings.Split(escapeRoff(str), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,57 @@
+package flagx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeCommand_GenerateDocs(t *testing.T) {
+	type globalOption struct {
+		Debug bool `short:"d" description:"enable debug log"`
+	}
+	type runOption struct {
+		Port  int      `short:"p" default:"80" env:"PORT" description:"the port | to listen"`
+		Token string   `required:"true" description:"the token"`
+		Name  string   `flag:"false" index:"0" required:"true" description:"the name"`
+		Files []string `flag:"false" description:"the files"`
+	}
+	cc := NewCompositeCommand("tool", "a tool for test")
+	assert.NoError(t, cc.AddPersistentFlags(&globalOption{}))
+	assert.NoError(t, cc.AddSubCommand("run", "run the task\nmore details", &runOption{}, func() error {
+		return nil
+	}))
+	dir := t.TempDir()
+	assert.NoError(t, cc.GenerateDocs(DocMarkdown, dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, "tool.md"))
+	assert.NoError(t, err)
+	doc := string(data)
+	assert.Contains(t, doc, "# tool\n")
+	assert.Contains(t, doc, "tool [flags] command")
+	assert.Contains(t, doc, "* [tool run](tool-run.md) - run the task\n")
+
+	data, err = os.ReadFile(filepath.Join(dir, "tool-run.md"))
+	assert.NoError(t, err)
+	doc = string(data)
+	assert.Contains(t, doc, "tool run [flags] name [files...]")
+	assert.Contains(t, doc, "| `name` | string | yes | the name |")
+	assert.Contains(t, doc, "| `-p, -port` | int | `80` | `$PORT` |  | the port \\| to listen |")
+	assert.Contains(t, doc, "| `-token` | string |  |  | yes | the token |")
+	assert.Contains(t, doc, "## Global Flags")
+	assert.Contains(t, doc, "* [tool](tool.md) - a tool for test")
+
+	assert.NoError(t, cc.GenerateDocs(DocMan, dir))
+	data, err = os.ReadFile(filepath.Join(dir, "tool-run.1"))
+	assert.NoError(t, err)
+	doc = string(data)
+	assert.Contains(t, doc, ".TH \"TOOL-RUN\" \"1\"\n")
+	assert.Contains(t, doc, "tool\\-run \\- run the task\n")
+	assert.Contains(t, doc, "\\fB\\-p, \\-port\\fR \\fIint\\fR\nthe port | to listen (default: 80, env: $PORT)\n")
+	assert.Contains(t, doc, ".SH GLOBAL OPTIONS\n")
+	assert.Contains(t, doc, ".BR tool (1)\n")
+
+	assert.Error(t, cc.GenerateDocs("html", dir))
+}
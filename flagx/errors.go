@@ -0,0 +1,138 @@
+package flagx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// ErrHelp is returned by Execute when help is requested, and usage message is shown.
+var ErrHelp = flag.ErrHelp
+
+// UnknownFlagError is returned when a flag arg is not defined by the command.
+type UnknownFlagError struct {
+	Flag        string   // the flag name, without leading dashes
+	Dashes      string   // the leading dashes typed, "-" or "--". If empty, "-" is used
+	Suggestions []string // the similar flags defined by the command, with leading dashes
+}
+
+func (e *UnknownFlagError) Error() string {
+	dashes := e.Dashes
+	if dashes == "" {
+		dashes = "-"
+	}
+	return "flag provided but not defined: " + dashes + e.Flag + suggestionMessage(e.Suggestions)
+}
+
+// UnknownCommandError is returned when the sub command is not found in composite command.
+type UnknownCommandError struct {
+	Command     string   // the sub command name
+	Suggestions []string // the similar sub command names, or all matched names if the prefix is ambiguous
+}
+
+func (e *UnknownCommandError) Error() string {
+	return "unknown command: " + e.Command + suggestionMessage(e.Suggestions)
+}
+
+// RequiredArgError is returned when a required arg is not set.
+type RequiredArgError struct {
+	Name  string // the arg name
+	Field string // the struct field name
+	Index int    // the position for non-flag arg, -1 for flag arg
+}
+
+func (e *RequiredArgError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("flag arg %v required but not set", e.Name)
+	}
+	return fmt.Sprintf("non-flag arg %v[%v] required but not set", e.Name, e.Index)
+}
+
+// InvalidValueError is returned when the value can not be set to an arg.
+type InvalidValueError struct {
+	Name  string // the arg name
+	Field string // the struct field name
+	Value string // the arg value
+	Err   error  // the cause
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for arg %v: %v", e.Value, e.Name, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// UnhandledArgsError is returned when there are non-flag args no field can receive.
+type UnhandledArgsError struct {
+	Args []string // the args not handled
+}
+
+func (e *UnhandledArgsError) Error() string {
+	return fmt.Sprintf("still has args not handled: %v", e.Args)
+}
+
+// convert the error returned by flag set parsing to typed error
+func toParseError(err error, fields []*fieldFlagValue) error {
+	for _, field := range fields {
+		if field.setErr != nil {
+			return field.setErr
+		}
+	}
+	const undefinedPrefix = "flag provided but not defined: -"
+	if msg := err.Error(); strings.HasPrefix(msg, undefinedPrefix) {
+		return &UnknownFlagError{Flag: strings.TrimPrefix(msg, undefinedPrefix), Dashes: "-"}
+	}
+	return fmt.Errorf("parse flag error: %w", err)
+}
+
+// GroupError is returned when flag args in a group are not set as required.
+type GroupError struct {
+	Group     string   // the group name
+	Names     []string // the flag arg names violate the group constraint
+	Exclusive bool     // if the group is mutually exclusive, else the flag args should be set together
+}
+
+func (e *GroupError) Error() string {
+	if e.Exclusive {
+		return fmt.Sprintf("flag args %v are mutually exclusive", strings.Join(e.Names, ", "))
+	}
+	return fmt.Sprintf("flag args %v should be set together", strings.Join(e.Names, ", "))
+}
+
+// ValidationError contains all violations found when validating arg values, such as *RequiredArgError,
+// *InvalidValueError, and *GroupError, and the parse errors such as *UnknownFlagError and *UnhandledArgsError.
+// It is returned only when there are more than one errors, a single error is returned directly.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	var sb strings.Builder
+	sb.WriteString("invalid args:")
+	for _, err := range e.Errors {
+		sb.WriteString("\n  ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap return all violations
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// As set target to the first violation matches it, for errors.As of Go versions not support Unwrap() []error
+func (e *ValidationError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
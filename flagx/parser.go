@@ -0,0 +1,200 @@
+package flagx
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+)
+
+// Syntax is the command line syntax for parsing flag args
+type Syntax int
+
+const (
+	// SyntaxDefault use the syntax of parent command, or SyntaxGo for root command
+	SyntaxDefault Syntax = iota
+	// SyntaxGo is the syntax of go flag package: flags are in -name or --name form,
+	// and all flag args should be before non-flag args.
+	SyntaxGo
+	// SyntaxGNU is the POSIX/GNU style syntax: long flags are in --name form, short flags are in -n form and can be
+	// combined like -vx, bool flags can be negated by --no-name, flag args and non-flag args can be interspersed,
+	// and "--" terminates flag args.
+	SyntaxGNU
+)
+
+// create a go flag set for parsing flag args. If multi fields have same name, the first one is used.
+func newFlagSet(fields []*fieldFlagValue) *flag.FlagSet {
+	flagSet := &flag.FlagSet{}
+	// errors and usage are printed by command
+	flagSet.SetOutput(io.Discard)
+	flagSet.Usage = func() {}
+	for _, ffv := range fields {
+		if flagSet.Lookup(ffv.name) == nil {
+			flagSet.Var(ffv, ffv.name, ffv.usage())
+		}
+		if ffv.short != "" && flagSet.Lookup(ffv.short) == nil {
+			flagSet.Var(ffv, ffv.short, ffv.usage())
+		}
+	}
+	return flagSet
+}
+
+// parse flag args, set values to fields, and return non-flag args.
+// If interspersed is false, stop parsing at the first non-flag arg.
+func parseArgs(arguments []string, fields []*fieldFlagValue, syntax Syntax, interspersed bool) ([]string, error) {
+	args, err := parseArgs0(arguments, fields, syntax, interspersed)
+	var unknownErr *UnknownFlagError
+	if errors.As(err, &unknownErr) {
+		unknownErr.Suggestions = suggestFlags(unknownErr.Flag, fields, syntax)
+	}
+	return args, err
+}
+
+func parseArgs0(arguments []string, fields []*fieldFlagValue, syntax Syntax, interspersed bool) ([]string, error) {
+	if syntax == SyntaxGNU {
+		return parseGNUArgs(arguments, fields, interspersed)
+	}
+	flagSet := newFlagSet(fields)
+	if err := flagSet.Parse(arguments); err != nil {
+		if err == flag.ErrHelp {
+			return nil, ErrHelp
+		}
+		return nil, toParseError(err, fields)
+	}
+	return flagSet.Args(), nil
+}
+
+// suggest flags similar to the unknown flag name. Not suggest for one char flags, which are usually short names.
+func suggestFlags(name string, fields []*fieldFlagValue, syntax Syntax) []string {
+	if len(name) < 2 {
+		return nil
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	dashes := "-"
+	if syntax == SyntaxGNU {
+		dashes = "--"
+	}
+	var suggestions []string
+	for _, s := range suggest(name, names) {
+		suggestions = append(suggestions, dashes+s)
+	}
+	return suggestions
+}
+
+var errMissingFlagValue = errors.New("flag needs an argument")
+
+// parse args in GNU syntax
+func parseGNUArgs(arguments []string, fields []*fieldFlagValue, interspersed bool) ([]string, error) {
+	var args []string
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		switch {
+		case arg == "--":
+			return append(args, arguments[i+1:]...), nil
+		case strings.HasPrefix(arg, "--"):
+			consumed, err := parseGNULongFlag(arg[2:], arguments[i+1:], fields)
+			if err != nil {
+				return nil, err
+			}
+			i += consumed
+		case len(arg) > 1 && arg[0] == '-':
+			consumed, err := parseGNUShortFlags(arg[1:], arguments[i+1:], fields)
+			if err != nil {
+				return nil, err
+			}
+			i += consumed
+		default:
+			if !interspersed {
+				return append(args, arguments[i:]...), nil
+			}
+			args = append(args, arg)
+		}
+	}
+	return args, nil
+}
+
+// parse a long flag without leading dashes, return the count of following args consumed as flag value
+func parseGNULongFlag(arg string, following []string, fields []*fieldFlagValue) (int, error) {
+	name, value, hasValue := arg, "", false
+	if idx := strings.IndexByte(arg, '='); idx >= 0 {
+		name, value, hasValue = arg[:idx], arg[idx+1:], true
+	}
+
+	field := findField(fields, name, false)
+	if field == nil && strings.HasPrefix(name, "no-") {
+		if f := findField(fields, name[3:], false); f != nil && f.IsBoolFlag() {
+			if hasValue {
+				return 0, &InvalidValueError{Name: f.name, Field: f.fieldName, Value: value,
+					Err: errors.New("negated flag can not have value")}
+			}
+			return 0, setGNUValue(f, "false")
+		}
+	}
+	if field == nil {
+		if name == "help" {
+			return 0, ErrHelp
+		}
+		return 0, &UnknownFlagError{Flag: name, Dashes: "--"}
+	}
+
+	switch {
+	case hasValue:
+		return 0, setGNUValue(field, value)
+	case field.IsBoolFlag():
+		return 0, setGNUValue(field, "true")
+	case len(following) > 0:
+		return 1, setGNUValue(field, following[0])
+	default:
+		return 0, &InvalidValueError{Name: field.name, Field: field.fieldName, Err: errMissingFlagValue}
+	}
+}
+
+// parse combined short flags without leading dash, return the count of following args consumed as flag value
+func parseGNUShortFlags(arg string, following []string, fields []*fieldFlagValue) (int, error) {
+	for i := 0; i < len(arg); i++ {
+		name := arg[i : i+1]
+		field := findField(fields, name, true)
+		if field == nil {
+			if name == "h" {
+				return 0, ErrHelp
+			}
+			return 0, &UnknownFlagError{Flag: name, Dashes: "-"}
+		}
+		if field.IsBoolFlag() {
+			if err := setGNUValue(field, "true"); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		// the remained chars are the value
+		if value := strings.TrimPrefix(arg[i+1:], "="); i+1 < len(arg) {
+			return 0, setGNUValue(field, value)
+		}
+		if len(following) > 0 {
+			return 1, setGNUValue(field, following[0])
+		}
+		return 0, &InvalidValueError{Name: field.name, Field: field.fieldName, Err: errMissingFlagValue}
+	}
+	return 0, nil
+}
+
+// set flag value in GNU syntax. For non-multi-value flags, the last value wins, such as "-v --no-verbose"
+func setGNUValue(field *fieldFlagValue, value string) error {
+	if !isMultiValueType(field._type) {
+		field.set = false
+	}
+	return field.Set(value)
+}
+
+// find field by long name, or short name
+func findField(fields []*fieldFlagValue, name string, short bool) *fieldFlagValue {
+	for _, f := range fields {
+		if short && f.short == name || !short && f.name == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package flagx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gnuOption struct {
+	Verbose bool     `short:"v"`
+	Extract bool     `short:"x"`
+	Color   bool     `default:"true"`
+	Port    int      `short:"p"`
+	Name    string   `short:"n"`
+	Files   []string `flag:"false"`
+}
+
+func newGNUCommand(option *gnuOption) *Command {
+	cmd, _ := NewCommand("my", "", option, func() error {
+		return nil
+	})
+	cmd.Syntax = SyntaxGNU
+	cmd.Stdout = &bytes.Buffer{}
+	return cmd
+}
+
+func TestCommand_gnuSyntax(t *testing.T) {
+	option := &gnuOption{}
+	assert.NoError(t, newGNUCommand(option).Execute([]string{"a", "-vxp", "80", "b", "--no-color", "--name=test", "c"}))
+	assert.True(t, option.Verbose)
+	assert.True(t, option.Extract)
+	assert.False(t, option.Color)
+	assert.Equal(t, 80, option.Port)
+	assert.Equal(t, "test", option.Name)
+	assert.Equal(t, []string{"a", "b", "c"}, option.Files)
+
+	option = &gnuOption{}
+	assert.NoError(t, newGNUCommand(option).Execute([]string{"-p8080", "-n", "test", "--", "-v", "--port"}))
+	assert.False(t, option.Verbose)
+	assert.Equal(t, 8080, option.Port)
+	assert.Equal(t, "test", option.Name)
+	assert.Equal(t, []string{"-v", "--port"}, option.Files)
+
+	option = &gnuOption{}
+	assert.NoError(t, newGNUCommand(option).Execute([]string{"--port", "1", "-"}))
+	assert.Equal(t, 1, option.Port)
+	assert.Equal(t, []string{"-"}, option.Files)
+
+	// the last value wins
+	option = &gnuOption{}
+	assert.NoError(t, newGNUCommand(option).Execute([]string{"-v", "--no-verbose", "--no-color", "--color", "-p", "1", "--port=2"}))
+	assert.False(t, option.Verbose)
+	assert.True(t, option.Color)
+	assert.Equal(t, 2, option.Port)
+	assert.NoError(t, newGNUCommand(option).Execute([]string{"--verbose", "--no-verbose"}))
+	assert.False(t, option.Verbose)
+
+	var unknownErr *UnknownFlagError
+	err := newGNUCommand(&gnuOption{}).Execute([]string{"-vz"})
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, "z", unknownErr.Flag)
+	err = newGNUCommand(&gnuOption{}).Execute([]string{"--no-port"})
+	assert.True(t, errors.As(err, &unknownErr))
+
+	var invalidErr *InvalidValueError
+	err = newGNUCommand(&gnuOption{}).Execute([]string{"--port"})
+	assert.True(t, errors.As(err, &invalidErr))
+	assert.Equal(t, "port", invalidErr.Name)
+
+	assert.Equal(t, ErrHelp, newGNUCommand(&gnuOption{}).Execute([]string{"-h"}))
+	assert.Equal(t, ErrHelp, newGNUCommand(&gnuOption{}).Execute([]string{"--help"}))
+}
+
+func TestCommand_shortName(t *testing.T) {
+	// short name also works for go syntax
+	option := &gnuOption{}
+	cmd, err := NewCommand("my", "", option, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, cmd.Execute([]string{"-v", "-p", "80", "a"}))
+	assert.True(t, option.Verbose)
+	assert.Equal(t, 80, option.Port)
+
+	_, err = NewCommand("my", "", &struct {
+		Port int `short:"port"`
+	}{}, func() error {
+		return nil
+	})
+	assert.Error(t, err)
+
+	_, err = NewCommand("my", "", &struct {
+		Port  int  `short:"p"`
+		Print bool `short:"p"`
+	}{}, func() error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestCompositeCommand_gnuSyntax(t *testing.T) {
+	type globalOption struct {
+		Debug bool `short:"d"`
+	}
+	globalOpt := &globalOption{}
+	option := &gnuOption{}
+	cc := NewCompositeCommand("tool", "")
+	cc.Syntax = SyntaxGNU
+	assert.NoError(t, cc.AddPersistentFlags(globalOpt))
+	assert.NoError(t, cc.AddSubCommand("run", "", option, func() error {
+		return nil
+	}))
+	assert.NoError(t, cc.Execute([]string{"-d", "run", "a", "-vp", "80"}))
+	assert.True(t, globalOpt.Debug)
+	assert.True(t, option.Verbose)
+	assert.Equal(t, 80, option.Port)
+	assert.Equal(t, []string{"a"}, option.Files)
+
+	var buf bytes.Buffer
+	cc.Stdout = &buf
+	assert.Equal(t, ErrHelp, cc.Execute([]string{"run", "--help"}))
+	assert.Regexp(t, "-p, --port +int", buf.String())
+}
@@ -0,0 +1,90 @@
+package flagx
+
+import (
+	"sort"
+	"strings"
+)
+
+// return candidates similar to input: the ones with minimal edit distance, and the ones start with input.
+// The most similar ones come first.
+func suggest(input string, candidates []string) []string {
+	maxDistance := (len(input) + 2) / 3
+	type suggestion struct {
+		value    string
+		distance int
+		prefixed bool
+	}
+	var suggestions []suggestion
+	for _, candidate := range candidates {
+		distance := editDistance(input, candidate)
+		prefixed := input != "" && strings.HasPrefix(candidate, input)
+		if distance <= maxDistance || prefixed {
+			suggestions = append(suggestions, suggestion{value: candidate, distance: distance, prefixed: prefixed})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+	var values []string
+	for _, s := range suggestions {
+		if s.distance == suggestions[0].distance || s.prefixed {
+			values = append(values, s.value)
+		}
+	}
+	return values
+}
+
+// the optimal string alignment distance of two strings: the count of insertions, deletions, substitutions
+// and transpositions of adjacent chars, to change a to b.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between ra[:i] and rb[:j]
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, minInt(d[i][j-1]+1, d[i-1][j-1]+cost))
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// the message part for suggestions, such as ", did you mean "status"?"
+func suggestionMessage(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return ", did you mean " + quoteJoin(suggestions) + "?"
+	default:
+		return ", did you mean one of " + quoteJoin(suggestions) + "?"
+	}
+}
+
+func quoteJoin(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, "'"+v+"'")
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,76 @@
+package flagx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_editDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("status", "status"))
+	assert.Equal(t, 1, editDistance("stauts", "status"))
+	assert.Equal(t, 1, editDistance("statu", "status"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+	assert.Equal(t, 3, editDistance("kitten", "sitting"))
+}
+
+func Test_suggest(t *testing.T) {
+	assert.Equal(t, []string{"status"}, suggest("statu", []string{"start", "status", "stop"}))
+	assert.Equal(t, []string{"stop", "start", "status"}, suggest("st", []string{"start", "status", "stop"}))
+	assert.Equal(t, []string{"status"}, suggest("stauts", []string{"start", "status", "stop"}))
+	assert.Nil(t, suggest("xyz", []string{"start", "status", "stop"}))
+}
+
+func TestCompositeCommand_suggestions(t *testing.T) {
+	handle := func() error {
+		return nil
+	}
+	cc := NewCompositeCommand("tool", "")
+	assert.NoError(t, cc.AddSubCommand("status", "", &struct{}{}, handle))
+	assert.NoError(t, cc.AddSubCommand("start", "", &struct{}{}, handle))
+	stop, err := NewCommand("stop", "", &struct {
+		Force   bool
+		Timeout int
+	}{}, handle)
+	assert.NoError(t, err)
+	stop.Aliases = []string{"halt"}
+	assert.NoError(t, cc.AddCommand(stop))
+
+	var commandErr *UnknownCommandError
+	err = cc.Execute([]string{"stauts"})
+	assert.True(t, errors.As(err, &commandErr))
+	assert.Equal(t, []string{"status"}, commandErr.Suggestions)
+	assert.Equal(t, "unknown command: stauts, did you mean 'status'?", err.Error())
+
+	var flagErr *UnknownFlagError
+	err = cc.Execute([]string{"stop", "-forse"})
+	assert.True(t, errors.As(err, &flagErr))
+	assert.Equal(t, []string{"-force"}, flagErr.Suggestions)
+	assert.Equal(t, "flag provided but not defined: -forse, did you mean '-force'?", err.Error())
+
+	assert.NoError(t, cc.Execute([]string{"halt", "-force"}))
+
+	gnu, err := NewCommand("gnu", "", &struct {
+		Port int
+	}{}, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	gnu.Syntax = SyntaxGNU
+	err = gnu.Execute([]string{"--prot", "80"})
+	assert.Equal(t, "flag provided but not defined: --prot, did you mean '--port'?", err.Error())
+
+	// prefix matching
+	assert.True(t, errors.As(cc.Execute([]string{"sto"}), &commandErr))
+	cc.PrefixMatching = true
+	assert.NoError(t, cc.Execute([]string{"sto"}))
+	assert.NoError(t, cc.Execute([]string{"ha"}))
+	err = cc.Execute([]string{"sta"})
+	assert.True(t, errors.As(err, &commandErr))
+	assert.Equal(t, []string{"status", "start"}, commandErr.Suggestions)
+
+	assert.Error(t, cc.AddSubCommand("halt", "", &struct{}{}, func() error {
+		return nil
+	}))
+}
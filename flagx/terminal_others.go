@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package flagx
+
+import "os"
+
+// the column count of terminal, or 0 if can not get
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,22 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package flagx
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// the column count of terminal, or 0 if can not get
+func terminalWidth(f *os.File) int {
+	var size struct {
+		rows, cols, xPixel, yPixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+	return int(size.cols)
+}
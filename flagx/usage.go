@@ -0,0 +1,282 @@
+package flagx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hsiafan/glow/stringx"
+)
+
+// ColorMode determine if the usage message is colored by ANSI escape codes
+type ColorMode int
+
+const (
+	// ColorAuto use the parent's color mode. For root command, use color if output is a terminal, and NO_COLOR env not set.
+	ColorAuto ColorMode = iota
+	// ColorAlways always use color
+	ColorAlways
+	// ColorNever never use color
+	ColorNever
+)
+
+const (
+	defaultUsageWidth = 80
+	minDescWidth      = 30 // if the description column is narrower than this, print description in next line
+	descIndent        = 8  // the indent of description, if it is printed in next line
+)
+
+const (
+	ansiBold  = "\x1b[1m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// for rendering usage message
+type usageWriter struct {
+	w     io.Writer
+	width int  // the max line width
+	color bool // use ANSI color
+}
+
+// one row of flag or sub command in usage message
+type usageRow struct {
+	name     string // flag names or command name
+	typeName string // the value type
+	desc     string // the description
+}
+
+func newUsageWriter(w io.Writer, mode ColorMode) *usageWriter {
+	terminal := isTerminal(w)
+	width := 0
+	if terminal {
+		width = terminalWidth(w.(*os.File))
+	}
+	if width <= 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if width <= 0 {
+		width = defaultUsageWidth
+	}
+	color := mode == ColorAlways
+	if mode == ColorAuto {
+		_, noColor := os.LookupEnv("NO_COLOR")
+		color = terminal && !noColor
+	}
+	return &usageWriter{w: w, width: width, color: color}
+}
+
+// if the writer is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (u *usageWriter) style(str string, style string) string {
+	if !u.color || str == "" {
+		return str
+	}
+	return style + str + ansiReset
+}
+
+func (u *usageWriter) println(line string) {
+	_, _ = fmt.Fprintln(u.w, line)
+}
+
+// print text wrapped by line width
+func (u *usageWriter) paragraph(text string) {
+	for _, line := range wrapText(text, u.width) {
+		u.println(line)
+	}
+	u.println("")
+}
+
+func (u *usageWriter) synopsis(path string, args string) {
+	u.println(u.style("Usage:", ansiBold) + " " + path + stringx.PrependIfNotEmpty(args, " "))
+}
+
+// print a section with title, and rows in aligned columns
+func (u *usageWriter) section(title string, rows []usageRow) {
+	if len(rows) == 0 {
+		return
+	}
+	u.println("")
+	u.println(u.style(title+":", ansiBold))
+
+	var nameWidth, typeWidth int
+	for _, row := range rows {
+		nameWidth = maxInt(nameWidth, len(row.name))
+		typeWidth = maxInt(typeWidth, len(row.typeName))
+	}
+	descCol := 2 + nameWidth + 2
+	if typeWidth > 0 {
+		descCol += typeWidth + 2
+	}
+	nextLine := u.width-descCol < minDescWidth
+
+	for _, row := range rows {
+		var sb strings.Builder
+		sb.WriteString("  ")
+		name := strings.TrimLeft(row.name, " ")
+		sb.WriteString(row.name[:len(row.name)-len(name)])
+		sb.WriteString(u.style(name, ansiCyan))
+		if typeWidth > 0 {
+			sb.WriteString(strings.Repeat(" ", nameWidth-len(row.name)+2))
+			sb.WriteString(row.typeName)
+			sb.WriteString(strings.Repeat(" ", typeWidth-len(row.typeName)))
+		} else {
+			sb.WriteString(strings.Repeat(" ", nameWidth-len(row.name)))
+		}
+		if row.desc == "" {
+			u.println(strings.TrimRight(sb.String(), " "))
+			continue
+		}
+
+		indent, width := descCol, u.width-descCol
+		if nextLine {
+			indent, width = descIndent, u.width-descIndent
+			u.println(strings.TrimRight(sb.String(), " "))
+			sb.Reset()
+			sb.WriteString(strings.Repeat(" ", indent))
+		} else {
+			sb.WriteString("  ")
+		}
+		for i, line := range wrapText(row.desc, width) {
+			if i > 0 {
+				sb.WriteString("\n")
+				sb.WriteString(strings.Repeat(" ", indent))
+			}
+			sb.WriteString(line)
+		}
+		u.println(sb.String())
+	}
+}
+
+// print flag args, grouped by group tag. Flags not in any group are under the title.
+func (u *usageWriter) flagSections(title string, fields []*fieldFlagValue, syntax Syntax) {
+	var groups []string
+	grouped := map[string][]*fieldFlagValue{}
+	for _, f := range fields {
+		if _, ok := grouped[f.group]; !ok && f.group != "" {
+			groups = append(groups, f.group)
+		}
+		grouped[f.group] = append(grouped[f.group], f)
+	}
+	u.section(title, flagRows(grouped[""], syntax))
+	for _, group := range groups {
+		u.section(group, flagRows(grouped[group], syntax))
+	}
+}
+
+func flagRows(fields []*fieldFlagValue, syntax Syntax) []usageRow {
+	hasShort := false
+	for _, f := range fields {
+		hasShort = hasShort || f.short != ""
+	}
+	var rows []usageRow
+	for _, f := range fields {
+		name := flagDisplayName(f, syntax)
+		if hasShort && f.short == "" {
+			// align the long names
+			name = "    " + name
+		}
+		rows = append(rows, usageRow{name: name, typeName: valueTypeName(f._type), desc: flagUsageDesc(f)})
+	}
+	return rows
+}
+
+// the description of flag, with default value, env and required state
+func flagUsageDesc(f *fieldFlagValue) string {
+	desc := f.description
+	if f.hasDefault && f.defaultValue != "" {
+		if singleValueType(f._type).Kind() == reflect.String && !isMultiValueType(f._type) {
+			desc += fmt.Sprintf(" (default %q)", f.defaultValue)
+		} else {
+			desc += fmt.Sprintf(" (default %v)", f.defaultValue)
+		}
+	}
+	if f.env != "" {
+		desc += " (env $" + f.env + ")"
+	}
+	if f.required && !f.hasDefault {
+		desc += " (required)"
+	}
+	return strings.TrimSpace(desc)
+}
+
+// wrap text by width. Line breaks in text are kept, and words longer than width are not split.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// the flag names shown in usage and docs, such as "-p, --port"
+func flagDisplayName(f *fieldFlagValue, syntax Syntax) string {
+	long := "-" + f.name
+	if syntax == SyntaxGNU {
+		long = "--" + f.name
+	}
+	if f.short == "" {
+		return long
+	}
+	return "-" + f.short + ", " + long
+}
+
+// the value type name shown in usage. Bool type return empty string.
+func valueTypeName(t reflect.Type) string {
+	if isBoolType(t) {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "duration"
+	case t == timeType:
+		return "time"
+	case t == urlType:
+		return "url"
+	case isTextValueType(t):
+		return "value"
+	case t.Kind() == reflect.Map:
+		return valueTypeName(t.Key()) + "=" + valueTypeName(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return valueTypeName(t.Elem())
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "float"
+	default:
+		return t.Kind().String()
+	}
+}
@@ -0,0 +1,71 @@
+package flagx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_wrapText(t *testing.T) {
+	assert.Equal(t, []string{"a bb", "ccc", "", "d"}, wrapText("a bb ccc\n\nd", 5))
+	assert.Equal(t, []string{"abcdef", "g"}, wrapText("abcdef g", 3))
+}
+
+func TestCommand_printUsage(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	type usageOption struct {
+		Host    string `group:"Network" description:"the host to connect"`
+		Port    int    `group:"Network" short:"p" default:"80" env:"PORT" description:"the port"`
+		Verbose bool   `short:"v" description:"show more logs, which is a long description to be wrapped"`
+		Token   string `required:"true"`
+		Name    string `flag:"false" index:"0" required:"true" description:"the name"`
+	}
+	cmd, err := NewCommand("my", "my command", &usageOption{}, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	cmd.printUsage(&buf)
+	assert.Equal(t, `my command
+
+Usage: my name
+
+Arguments:
+  name  string  the name (required)
+
+Flags:
+  -v, -verbose          show more logs, which is a long description to be
+                        wrapped
+      -token    string  (required)
+
+Network:
+      -host  string  the host to connect
+  -p, -port  int     the port (default 80) (env $PORT)
+`, buf.String())
+
+	buf.Reset()
+	cmd.Color = ColorAlways
+	cmd.printUsage(&buf)
+	assert.Contains(t, buf.String(), ansiBold+"Network:"+ansiReset)
+	assert.Contains(t, buf.String(), ansiCyan+"-host"+ansiReset)
+}
+
+func TestCompositeCommand_printUsage(t *testing.T) {
+	cc := NewCompositeCommand("tool", "")
+	assert.NoError(t, cc.AddSubCommand("run", "run the task", &struct{}{}, func() error {
+		return nil
+	}))
+	assert.NoError(t, cc.AddSubCommand("stop", "stop the task", &struct{}{}, func() error {
+		return nil
+	}))
+	var buf bytes.Buffer
+	cc.printUsage(&buf)
+	assert.Equal(t, `Usage: tool command
+
+Commands:
+  run   run the task
+  stop  stop the task
+`, buf.String())
+}
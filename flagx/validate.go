@@ -0,0 +1,333 @@
+package flagx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	floatx2 "github.com/hsiafan/glow/mathx/floatx"
+)
+
+// validator check one single value of arg
+type validator func(value reflect.Value) error
+
+// build validators for a struct field by tags
+func newValidators(field reflect.StructField) ([]validator, error) {
+	var validators []validator
+	t := singleValueType(field.Type)
+
+	if v, ok := field.Tag.Lookup(choicesField); ok {
+		choices := strings.Split(v, ",")
+		validators = append(validators, func(value reflect.Value) error {
+			s := valueString(value)
+			for _, choice := range choices {
+				if s == choice {
+					return nil
+				}
+			}
+			return fmt.Errorf("should be one of %v", choices)
+		})
+	}
+
+	for _, name := range []string{minField, maxField} {
+		v, ok := field.Tag.Lookup(name)
+		if !ok {
+			continue
+		}
+		compare, err := newComparator(t, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v tag: %w", name, err)
+		}
+		isMin := name == minField
+		validators = append(validators, func(value reflect.Value) error {
+			c := compare(value)
+			if isMin && c < 0 {
+				return fmt.Errorf("should not be less than %v", v)
+			}
+			if !isMin && c > 0 {
+				return fmt.Errorf("should not be greater than %v", v)
+			}
+			return nil
+		})
+	}
+
+	if v, ok := field.Tag.Lookup(patternField); ok {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern tag: %w", err)
+		}
+		validators = append(validators, func(value reflect.Value) error {
+			if !re.MatchString(valueString(value)) {
+				return fmt.Errorf("should match pattern %v", v)
+			}
+			return nil
+		})
+	}
+
+	if v, ok := field.Tag.Lookup(existsField); ok {
+		if v != existsFile && v != existsDir {
+			return nil, fmt.Errorf("invalid exists tag value: %v", v)
+		}
+		validators = append(validators, func(value reflect.Value) error {
+			info, err := os.Stat(valueString(value))
+			if err != nil {
+				return fmt.Errorf("%v not exists", v)
+			}
+			if v == existsDir && !info.IsDir() {
+				return errors.New("should be a dir")
+			}
+			if v == existsFile && info.IsDir() {
+				return errors.New("should be a file")
+			}
+			return nil
+		})
+	}
+	return validators, nil
+}
+
+// create a func, which compare the value with the bound, return -1, 0, 1 for less, equal and greater.
+func newComparator(t reflect.Type, bound string) (func(value reflect.Value) int, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return nil, err
+		}
+		return func(value reflect.Value) int {
+			return compareInt64(value.Int(), int64(d))
+		}, nil
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(value reflect.Value) int {
+			return compareInt64(value.Int(), b)
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return func(value reflect.Value) int {
+			v := value.Uint()
+			if v < b {
+				return -1
+			} else if v > b {
+				return 1
+			}
+			return 0
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		b, err := floatx2.Parse64(bound)
+		if err != nil {
+			return nil, err
+		}
+		return func(value reflect.Value) int {
+			v := value.Float()
+			if v < b {
+				return -1
+			} else if v > b {
+				return 1
+			}
+			return 0
+		}, nil
+	default:
+		return nil, fmt.Errorf("only numeric and duration type can have bound, but got %v", t)
+	}
+}
+
+func compareInt64(v int64, b int64) int {
+	if v < b {
+		return -1
+	} else if v > b {
+		return 1
+	}
+	return 0
+}
+
+// the type of single value: the pointer is dereferenced, and for slice and map type, the element type is used.
+func singleValueType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isMultiValueType(t) {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// call f for each single value. Nil pointers are skipped, slice elements and map values are visited.
+func forEachSingleValue(value reflect.Value, f func(v reflect.Value)) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if !isMultiValueType(value.Type()) {
+		f(value)
+		return
+	}
+	if value.Kind() == reflect.Map {
+		iter := value.MapRange()
+		for iter.Next() {
+			forEachSingleValue(iter.Value(), f)
+		}
+		return
+	}
+	for i := 0; i < value.Len(); i++ {
+		forEachSingleValue(value.Index(i), f)
+	}
+}
+
+// the string form of a value, for validating
+func valueString(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	if value.CanAddr() {
+		if s, ok := value.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// run validators on all values of a field, and return violations
+func validateValue(value reflect.Value, validators []validator, name string, fieldName string) []error {
+	var errs []error
+	forEachSingleValue(value, func(v reflect.Value) {
+		for _, validate := range validators {
+			if err := validate(v); err != nil {
+				errs = append(errs, &InvalidValueError{Name: name, Field: fieldName, Value: valueString(v), Err: err})
+			}
+		}
+	})
+	return errs
+}
+
+// a group of flag args, which are mutually exclusive, or should be set together
+type fieldGroup struct {
+	name      string
+	exclusive bool // mutually exclusive if true, else should be set together
+	fields    []*fieldFlagValue
+}
+
+func (g *fieldGroup) validate() error {
+	var setNames []string
+	var names []string
+	for _, f := range g.fields {
+		names = append(names, f.name)
+		if f.set {
+			setNames = append(setNames, f.name)
+		}
+	}
+	if g.exclusive && len(setNames) > 1 {
+		return &GroupError{Group: g.name, Names: setNames, Exclusive: true}
+	}
+	if !g.exclusive && len(setNames) > 0 && len(setNames) < len(names) {
+		return &GroupError{Group: g.name, Names: names}
+	}
+	return nil
+}
+
+// add field to groups by exclusive and together tags
+func (o *optionFields) addToGroups(ffv *fieldFlagValue, field reflect.StructField) {
+	for _, tag := range []string{exclusiveField, togetherField} {
+		v, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		exclusive := tag == exclusiveField
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			group := o.findGroup(name, exclusive)
+			if group == nil {
+				group = &fieldGroup{name: name, exclusive: exclusive}
+				o.groups = append(o.groups, group)
+			}
+			group.fields = append(group.fields, ffv)
+		}
+	}
+}
+
+func (o *optionFields) findGroup(name string, exclusive bool) *fieldGroup {
+	for _, g := range o.groups {
+		if g.name == name && g.exclusive == exclusive {
+			return g
+		}
+	}
+	return nil
+}
+
+// validate all arg values, and return all violations, with the parse errors.
+// If flagsParsed is false, args after the bad one are not parsed, so unset flag args and non-flag args are not checked.
+// If there is only one error, it is returned directly.
+func (c *Command) validate(parseErrs []error, flagsParsed bool) error {
+	errs := parseErrs
+	for _, field := range c.allFlagFields() {
+		if field.setErr != nil {
+			// already reported as parse error
+			continue
+		}
+		if !field.set && !flagsParsed {
+			continue
+		}
+		if !field.set && !field.hasDefault {
+			if field.required {
+				errs = append(errs, &RequiredArgError{Name: field.name, Field: field.fieldName, Index: -1})
+			}
+			continue
+		}
+		errs = append(errs, validateValue(field.value, field.validators, field.name, field.fieldName)...)
+	}
+	for idx := 0; flagsParsed && idx < len(c.positionalFields); idx++ {
+		field := c.positionalFields[idx]
+		if field.setErr != nil {
+			continue
+		}
+		if !field.set {
+			if field.required {
+				errs = append(errs, &RequiredArgError{Name: field.name, Field: field.fieldName, Index: field.index})
+			}
+			continue
+		}
+		errs = append(errs, validateValue(field.value, field.validators, field.name, field.fieldName)...)
+	}
+	if f := c.remainFields; f != nil && flagsParsed {
+		errs = append(errs, validateValue(f.value, f.validators, f.name, f.fieldName)...)
+	}
+	groups := c.groups
+	if c.parent != nil {
+		groups = append(append([]*fieldGroup(nil), groups...), c.parent.allPersistentGroups()...)
+	}
+	for _, g := range groups {
+		if !g.exclusive && !flagsParsed {
+			// the other flag args of the group may not be parsed
+			continue
+		}
+		if err := g.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return &ValidationError{Errors: errs}
+}
@@ -0,0 +1,114 @@
+package flagx
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand_validate(t *testing.T) {
+	type ValidateOption struct {
+		Format  string        `choices:"json,yaml" default:"json"`
+		Level   int           `min:"1" max:"5"`
+		Timeout time.Duration `max:"1m"`
+		Name    string        `pattern:"^[a-z]+$"`
+		Dir     string        `exists:"dir"`
+		Tags    []string      `choices:"a,b"`
+		JSON    bool          `name:"json" exclusive:"output"`
+		YAML    bool          `name:"yaml" exclusive:"output"`
+		User    string        `together:"auth"`
+		Pass    string        `together:"auth"`
+		Port    int           `required:"true"`
+	}
+
+	newCommand := func() *Command {
+		cmd, err := NewCommand("my", "", &ValidateOption{}, func() error {
+			return nil
+		})
+		assert.NoError(t, err)
+		return cmd
+	}
+
+	assert.NoError(t, newCommand().Execute([]string{"-port", "80", "-level", "5", "-name", "abc", "-dir", os.TempDir(),
+		"-tags", "a", "-json", "-user", "u", "-pass", "p"}))
+
+	err := newCommand().Execute([]string{"-format", "xml", "-level", "0", "-timeout", "2m", "-name", "A",
+		"-dir", "/not/exists", "-tags", "c", "-json", "-yaml", "-user", "u"})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Errors, 9)
+
+	var requiredErr *RequiredArgError
+	assert.True(t, errors.As(err, &requiredErr))
+	assert.Equal(t, "port", requiredErr.Name)
+	var groupErr *GroupError
+	assert.True(t, errors.As(err, &groupErr))
+	assert.Equal(t, "output", groupErr.Group)
+	assert.Equal(t, []string{"json", "yaml"}, groupErr.Names)
+
+	// single violation is returned directly
+	err = newCommand().Execute([]string{})
+	_, ok := err.(*RequiredArgError)
+	assert.True(t, ok, err)
+	assert.True(t, validationErr.As(&requiredErr))
+
+	// parse errors are reported with violations
+	err = newCommand().Execute([]string{"-json", "-yaml", "-level", "abc"})
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Errors, 2)
+	var invalidErr *InvalidValueError
+	assert.True(t, errors.As(err, &invalidErr))
+	assert.Equal(t, "level", invalidErr.Name)
+	assert.True(t, errors.As(err, &groupErr))
+
+	_, err = NewCommand("my", "", &struct {
+		Name string `min:"1"`
+	}{}, func() error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestCommand_validateAfterParseError(t *testing.T) {
+	type Option struct {
+		Port int    `required:"true"`
+		Name string `flag:"false" index:"0" required:"true"`
+	}
+	for _, syntax := range []Syntax{SyntaxGo, SyntaxGNU} {
+		cmd, err := NewCommand("my", "", &Option{}, func() error {
+			return nil
+		})
+		assert.NoError(t, err)
+		cmd.Syntax = syntax
+
+		// the args after the unknown flag are not parsed, should not be reported as missing
+		err = cmd.Execute([]string{"--bogus", "--port", "80", "x"})
+		var unknownErr *UnknownFlagError
+		assert.True(t, errors.As(err, &unknownErr))
+		var validationErr *ValidationError
+		assert.False(t, errors.As(err, &validationErr), err)
+	}
+}
+
+func TestCommand_validatePersistentGroups(t *testing.T) {
+	type GlobalOption struct {
+		JSON bool `name:"json" exclusive:"output"`
+		YAML bool `name:"yaml" exclusive:"output"`
+	}
+	root := NewCompositeCommand("root", "")
+	assert.NoError(t, root.AddPersistentFlags(&GlobalOption{}))
+	sub, err := NewCommand("sub", "", &struct{}{}, func() error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, root.AddCommand(sub))
+
+	assert.NoError(t, root.Execute([]string{"sub", "-json"}))
+	err = root.Execute([]string{"-json", "sub", "-yaml"})
+	var groupErr *GroupError
+	assert.True(t, errors.As(err, &groupErr))
+	assert.Equal(t, []string{"json", "yaml"}, groupErr.Names)
+}
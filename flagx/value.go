@@ -0,0 +1,250 @@
+package flagx
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	floatx2 "github.com/hsiafan/glow/mathx/floatx"
+	intx2 "github.com/hsiafan/glow/mathx/intx"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	urlType             = reflect.TypeOf(url.URL{})
+)
+
+// if the type is parsed from text as a whole, by flag.Value, encoding.TextUnmarshaler, or special handling
+func isTextValueType(t reflect.Type) bool {
+	if t == timeType || t == urlType {
+		return true
+	}
+	pt := reflect.PtrTo(t)
+	return pt.Implements(flagValueType) || pt.Implements(textUnmarshalerType)
+}
+
+// if the type is slice or map, which can receive multi values
+func isMultiValueType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && !isTextValueType(t)
+}
+
+// if the type is struct(or pointer to struct) whose fields are parsed as args
+func isNestedStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isTextValueType(t)
+}
+
+func isBoolType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// check if the type can be used for flag arg field
+func checkFieldType(t reflect.Type) error {
+	if !isMultiValueType(t) {
+		return checkValueType(t)
+	}
+	if t.Kind() == reflect.Map {
+		if err := checkValueType(t.Key()); err != nil {
+			return err
+		}
+	}
+	return checkValueType(t.Elem())
+}
+
+// check if the type can be used for single value
+func checkValueType(t reflect.Type) error {
+	if isTextValueType(t) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t.Elem().Kind() == reflect.Ptr {
+			return fmt.Errorf("unsupported type: %v", t)
+		}
+		return checkValueType(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %v", t)
+	}
+}
+
+// add one value to slice or map. For map, the str should be in key=value form.
+func addValue(str string, value reflect.Value, layout string) error {
+	t := value.Type()
+	if t.Kind() == reflect.Map {
+		idx := strings.IndexByte(str, '=')
+		if idx < 0 {
+			return errors.New("map value should be in key=value form")
+		}
+		key := reflect.New(t.Key()).Elem()
+		if err := setValue(str[:idx], key, ""); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if err := setValue(str[idx+1:], elem, layout); err != nil {
+			return err
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(t))
+		}
+		value.SetMapIndex(key, elem)
+		return nil
+	}
+	elem := reflect.New(t.Elem()).Elem()
+	if err := setValue(str, elem, layout); err != nil {
+		return err
+	}
+	value.Set(reflect.Append(value, elem))
+	return nil
+}
+
+// set one arg field, the value converted from str input.
+// The layout is used for parsing time.Time value, if not set, RFC3339 is used.
+func setValue(str string, value reflect.Value, layout string) error {
+	switch value.Type() {
+	case durationType:
+		v, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(v))
+		return nil
+	case timeType:
+		if layout != "" {
+			v, err := time.Parse(layout, str)
+			if err != nil {
+				return err
+			}
+			value.Set(reflect.ValueOf(v))
+			return nil
+		}
+	case urlType:
+		v, err := url.Parse(str)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(*v))
+		return nil
+	}
+
+	if value.CanAddr() {
+		switch v := value.Addr().Interface().(type) {
+		case flag.Value:
+			return v.Set(str)
+		case encoding.TextUnmarshaler:
+			return v.UnmarshalText([]byte(str))
+		}
+	}
+
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+		if err := setValue(str, elem.Elem(), layout); err != nil {
+			return err
+		}
+		value.Set(elem)
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(str)
+	case reflect.Int:
+		v, err := intx2.Parse(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(v))
+	case reflect.Int8:
+		v, err := intx2.Parse8(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(v))
+	case reflect.Int16:
+		v, err := intx2.Parse16(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(v))
+	case reflect.Int32:
+		v, err := intx2.Parse32(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(v))
+	case reflect.Int64:
+		v, err := intx2.Parse64(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(v)
+	case reflect.Uint:
+		v, err := intx2.ParseUnsigned(str)
+		if err != nil {
+			return err
+		}
+		value.SetUint(uint64(v))
+	case reflect.Uint8:
+		v, err := intx2.ParseUnsigned8(str)
+		if err != nil {
+			return err
+		}
+		value.SetUint(uint64(v))
+	case reflect.Uint16:
+		v, err := intx2.ParseUnsigned16(str)
+		if err != nil {
+			return err
+		}
+		value.SetUint(uint64(v))
+	case reflect.Uint32:
+		v, err := intx2.ParseUnsigned32(str)
+		if err != nil {
+			return err
+		}
+		value.SetUint(uint64(v))
+	case reflect.Uint64:
+		v, err := intx2.ParseUnsigned64(str)
+		if err != nil {
+			return err
+		}
+		value.SetUint(v)
+	case reflect.Float32:
+		v, err := floatx2.Parse32(str)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(float64(v))
+	case reflect.Float64:
+		v, err := floatx2.Parse64(str)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		value.SetBool(v)
+	default:
+		return fmt.Errorf("unsupported field type: %v", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package flagx
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setValue(t *testing.T) {
+	var ip net.IP
+	assert.NoError(t, setValue("127.0.0.1", reflect.ValueOf(&ip).Elem(), ""))
+	assert.Equal(t, "127.0.0.1", ip.String())
+
+	var u url.URL
+	assert.NoError(t, setValue("https://example.com/path", reflect.ValueOf(&u).Elem(), ""))
+	assert.Equal(t, "example.com", u.Host)
+
+	var tm time.Time
+	assert.NoError(t, setValue("2020-01-02", reflect.ValueOf(&tm).Elem(), "2006-01-02"))
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), tm)
+	assert.NoError(t, setValue("2020-01-02T03:04:05Z", reflect.ValueOf(&tm).Elem(), ""))
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), tm)
+
+	var p *int
+	assert.NoError(t, setValue("10", reflect.ValueOf(&p).Elem(), ""))
+	assert.Equal(t, 10, *p)
+}
+
+func Test_addValue(t *testing.T) {
+	var m map[string]int
+	assert.NoError(t, addValue("a=1", reflect.ValueOf(&m).Elem(), ""))
+	assert.NoError(t, addValue("b=2", reflect.ValueOf(&m).Elem(), ""))
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, m)
+	assert.Error(t, addValue("c", reflect.ValueOf(&m).Elem(), ""))
+}
+
+func Test_checkFieldType(t *testing.T) {
+	assert.NoError(t, checkFieldType(reflect.TypeOf(map[string]string{})))
+	assert.NoError(t, checkFieldType(reflect.TypeOf(net.IP{})))
+	assert.NoError(t, checkFieldType(reflect.TypeOf([]*int{})))
+	assert.Error(t, checkFieldType(reflect.TypeOf([][]int{})))
+	assert.Error(t, checkFieldType(reflect.TypeOf(map[string][]string{})))
+	assert.Error(t, checkFieldType(reflect.TypeOf(struct{}{})))
+}
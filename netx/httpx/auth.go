@@ -0,0 +1,413 @@
+package httpx
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hsiafan/glow/hashx"
+	"github.com/hsiafan/glow/iox"
+	"github.com/hsiafan/glow/timex/durationx"
+)
+
+// Authenticator set credentials for requests, and handle the 401 challenges.
+// Implementations should be safe for concurrent use.
+type Authenticator interface {
+	// Authenticate set credentials to the request, usually the Authorization header.
+	Authenticate(req *http.Request) error
+	// Challenge is called when got a 401 response for the request. Return true if credentials are updated,
+	// and the request should be sent again.
+	Challenge(req *http.Request, resp *http.Response) (bool, error)
+}
+
+// Auth set the authenticator for all requests sent by this client.
+// If got a 401 response and the authenticator accept the challenge, the request is sent again once.
+// The authenticator is implemented as a interceptor, appended to the client interceptors.
+func Auth(authenticator Authenticator) ClientOption {
+	return Intercept(newAuthInterceptor(authenticator))
+}
+
+// WithAuth set the authenticator for one request.
+func WithAuth(authenticator Authenticator) RequestOption {
+	return WithInterceptors(newAuthInterceptor(authenticator))
+}
+
+func newAuthInterceptor(authenticator Authenticator) Interceptor {
+	return func(req *http.Request, next Invoker) (*http.Response, error) {
+		// clone the request, the headers set by authenticator should not leak to next attempts
+		r := req.Clone(req.Context())
+		if err := authenticator.Authenticate(r); err != nil {
+			return nil, err
+		}
+		resp, err := next(r)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+		retry, err := authenticator.Challenge(r, resp)
+		if err != nil || !retry {
+			return resp, err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		iox.Close(resp.Body)
+
+		if r, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+		if err := authenticator.Authenticate(r); err != nil {
+			return nil, err
+		}
+		return next(r)
+	}
+}
+
+// BearerToken is a Authenticator set static bearer token
+type BearerToken string
+
+// Authenticate set Authorization header
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set(HeaderAuthorization, "Bearer "+string(t))
+	return nil
+}
+
+// Challenge not retry for static token
+func (t BearerToken) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	return false, nil
+}
+
+// DigestAuth is a Authenticator for HTTP Digest Access Authentication(RFC 7616).
+// The first request is sent without credentials, and sent again with credentials computed from the challenge.
+// Following requests reuse the challenge.
+type DigestAuth struct {
+	user     string
+	password string
+
+	lock      sync.Mutex
+	challenge *digestChallenge
+	nc        int // the nonce count
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string // "auth" or empty
+	userhash  bool
+}
+
+// NewDigestAuth create a digest authenticator
+func NewDigestAuth(user, password string) *DigestAuth {
+	return &DigestAuth{user: user, password: password}
+}
+
+// Authenticate set Authorization header if have got a challenge
+func (d *DigestAuth) Authenticate(req *http.Request) error {
+	d.lock.Lock()
+	challenge := d.challenge
+	d.nc++
+	nc := d.nc
+	d.lock.Unlock()
+	if challenge == nil {
+		return nil
+	}
+
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(challenge.algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		return hashx.HashBytes([]byte(s), newHash).ToHex()
+	}
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	ncValue := fmt.Sprintf("%08x", nc)
+	uri := req.URL.RequestURI()
+
+	ha1 := h(d.user + ":" + challenge.realm + ":" + d.password)
+	if strings.HasSuffix(strings.ToLower(challenge.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + challenge.nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+	var response string
+	if challenge.qop == "" {
+		response = h(ha1 + ":" + challenge.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + challenge.nonce + ":" + ncValue + ":" + cnonce + ":" + challenge.qop + ":" + ha2)
+	}
+
+	user := d.user
+	if challenge.userhash {
+		user = h(d.user + ":" + challenge.realm)
+	}
+	var sb strings.Builder
+	sb.WriteString(`Digest username="` + user + `", realm="` + challenge.realm + `", nonce="` + challenge.nonce +
+		`", uri="` + uri + `", response="` + response + `"`)
+	if challenge.algorithm != "" {
+		sb.WriteString(", algorithm=" + challenge.algorithm)
+	}
+	if challenge.opaque != "" {
+		sb.WriteString(`, opaque="` + challenge.opaque + `"`)
+	}
+	if challenge.qop != "" {
+		sb.WriteString(", qop=" + challenge.qop + ", nc=" + ncValue + `, cnonce="` + cnonce + `"`)
+	}
+	if challenge.userhash {
+		sb.WriteString(", userhash=true")
+	}
+	req.Header.Set(HeaderAuthorization, sb.String())
+	return nil
+}
+
+// Challenge parse the Digest challenge from WWW-Authenticate header.
+// Not retry if the request already used the same nonce, and the nonce is not stale, which means the credentials are wrong.
+func (d *DigestAuth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	for _, value := range resp.Header.Values(HeaderWWWAuthenticate) {
+		scheme, params := parseAuthChallenge(value)
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		algorithm := params["algorithm"]
+		switch strings.ToUpper(algorithm) {
+		case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+		default:
+			continue
+		}
+		qop := ""
+		if qopOptions, ok := params["qop"]; ok {
+			for _, option := range strings.Split(qopOptions, ",") {
+				if strings.TrimSpace(option) == "auth" {
+					qop = "auth"
+				}
+			}
+			if qop == "" {
+				// only auth-int, not supported
+				continue
+			}
+		}
+		challenge := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: algorithm,
+			qop:       qop,
+			userhash:  strings.EqualFold(params["userhash"], "true"),
+		}
+		sentNonce := ""
+		if _, sentParams := parseAuthChallenge(req.Header.Get(HeaderAuthorization)); sentParams != nil {
+			sentNonce = sentParams["nonce"]
+		}
+		if sentNonce == challenge.nonce && !strings.EqualFold(params["stale"], "true") {
+			return false, nil
+		}
+		d.lock.Lock()
+		d.challenge = challenge
+		d.nc = 0
+		d.lock.Unlock()
+		return true, nil
+	}
+	return false, nil
+}
+
+// OAuth2Config is the config for OAuth2 authenticator
+type OAuth2Config struct {
+	TokenURL     string   // the token endpoint
+	ClientID     string   // the client id
+	ClientSecret string   // the client secret
+	Scopes       []string // the scopes requested
+	// RefreshToken if set, use refresh token grant to get access tokens; else use client credentials grant.
+	RefreshToken string
+	// ExpiryDelta is how early a token should be refreshed before it expires. Default is 10 seconds.
+	ExpiryDelta time.Duration
+	// Client is used to send token requests. If nil, a new Client is used.
+	Client *Client
+}
+
+// OAuth2Auth is a Authenticator, fetch access token by OAuth2 client credentials or refresh token grant,
+// cache the token and refresh it before expiry.
+type OAuth2Auth struct {
+	config *OAuth2Config
+	client *Client
+
+	lock         sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time // zero if never expire
+}
+
+// OAuth2Token is the token response of OAuth2 token endpoint
+type OAuth2Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// OAuth2Error is the error response of OAuth2 token endpoint
+type OAuth2Error struct {
+	ErrorCode        string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.ErrorDescription == "" {
+		return "oauth2 error: " + e.ErrorCode
+	}
+	return "oauth2 error: " + e.ErrorCode + ", " + e.ErrorDescription
+}
+
+// NewOAuth2Auth create a OAuth2 authenticator
+func NewOAuth2Auth(config *OAuth2Config) *OAuth2Auth {
+	client := config.Client
+	if client == nil {
+		client = NewClient()
+	}
+	return &OAuth2Auth{config: config, client: client, refreshToken: config.RefreshToken}
+}
+
+// Authenticate set Authorization header with cached access token, fetch a new token if expired
+func (o *OAuth2Auth) Authenticate(req *http.Request) error {
+	token, err := o.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderAuthorization, "Bearer "+token)
+	return nil
+}
+
+// Challenge invalidate the cached token, if the request used it
+func (o *OAuth2Auth) Challenge(req *http.Request, resp *http.Response) (bool, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if req.Header.Get(HeaderAuthorization) == "Bearer "+o.accessToken {
+		o.accessToken = ""
+	}
+	return true, nil
+}
+
+// Token return the cached access token, or fetch a new one if not exists or will expire soon
+func (o *OAuth2Auth) Token() (string, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	expiryDelta := durationOrDefault(o.config.ExpiryDelta, durationx.Seconds(10))
+	if o.accessToken != "" && (o.expiry.IsZero() || time.Now().Add(expiryDelta).Before(o.expiry)) {
+		return o.accessToken, nil
+	}
+
+	var token *OAuth2Token
+	var err error
+	if o.refreshToken != "" {
+		token, err = o.fetchToken(NewParam("grant_type", "refresh_token"), NewParam("refresh_token", o.refreshToken))
+		if err != nil && o.config.RefreshToken == "" {
+			// refresh token issued in client credentials flow may expire, fallback to get a new one
+			token, err = o.fetchToken(NewParam("grant_type", "client_credentials"))
+		}
+	} else {
+		token, err = o.fetchToken(NewParam("grant_type", "client_credentials"))
+	}
+	if err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("oauth2 token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "Bearer") {
+		return "", errors.New("unsupported oauth2 token type: " + token.TokenType)
+	}
+	o.accessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		o.refreshToken = token.RefreshToken
+	}
+	o.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expiry = time.Now().Add(durationx.Seconds(int(token.ExpiresIn)))
+	}
+	return o.accessToken, nil
+}
+
+func (o *OAuth2Auth) fetchToken(params ...*Param) (*OAuth2Token, error) {
+	if len(o.config.Scopes) > 0 {
+		params = append(params, NewParam("scope", strings.Join(o.config.Scopes, " ")))
+	}
+	var token OAuth2Token
+	var oauthErr OAuth2Error
+	_, err := o.client.Post(o.config.TokenURL, NewFormBody(params...), BasicAuth(o.config.ClientID, o.config.ClientSecret),
+		SetHeader(HeaderAccept, MimetypeJson)).DecodeJSONOrError(&token, &oauthErr)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && oauthErr.ErrorCode != "" {
+		return nil, &oauthErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// parse WWW-Authenticate or Authorization header value, like: Digest realm="test", qop="auth,auth-int"
+func parseAuthChallenge(value string) (string, map[string]string) {
+	value = strings.TrimSpace(value)
+	idx := strings.IndexByte(value, ' ')
+	if idx < 0 {
+		return value, nil
+	}
+	scheme, rest := value[:idx], value[idx+1:]
+	params := map[string]string{}
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return scheme, params
+		}
+		name := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimLeft(rest[eq+1:], " ")
+		var paramValue string
+		if strings.HasPrefix(rest, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				sb.WriteByte(rest[i])
+			}
+			paramValue = sb.String()
+			rest = rest[minInt(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			paramValue = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		params[name] = paramValue
+	}
+}
+
+func randomHex(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hashx.HashResult(data).ToHex(), nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+package httpx
+
+import (
+	"crypto/md5"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hsiafan/glow/hashx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(HeaderAuthorization)))
+	}))
+	defer server.Close()
+
+	_, body, err := NewClient(Auth(BearerToken("abc"))).Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc", body)
+}
+
+func TestDigestAuth(t *testing.T) {
+	const realm, nonce = "test", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		scheme, params := parseAuthChallenge(r.Header.Get(HeaderAuthorization))
+		h := func(s string) string {
+			return hashx.HashBytes([]byte(s), md5.New).ToHex()
+		}
+		if scheme == "Digest" {
+			ha1 := h(params["username"] + ":" + realm + ":secret")
+			ha2 := h(r.Method + ":" + params["uri"])
+			expected := h(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+			if params["response"] == expected && params["uri"] == r.URL.RequestURI() {
+				_, _ = w.Write([]byte("ok"))
+				return
+			}
+		}
+		w.Header().Set(HeaderWWWAuthenticate, `Digest realm="`+realm+`", qop="auth,auth-int", nonce="`+nonce+`", opaque="x"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(Auth(NewDigestAuth("user", "secret")))
+	_, body, err := client.Post(server.URL+"/path?q=1", NewStringBody("data", MimeTypePlainText)).CheckStatus().ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", body)
+	assert.Equal(t, int32(2), count)
+
+	// reuse the challenge
+	_, err = client.Get(server.URL).CheckStatus().DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), count)
+
+	// wrong password, not retry
+	atomic.StoreInt32(&count, 0)
+	header, err := NewClient(Auth(NewDigestAuth("user", "wrong"))).Get(server.URL).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, header.StatusCode)
+	assert.Equal(t, int32(2), count)
+}
+
+func TestOAuth2Auth(t *testing.T) {
+	var tokenCount int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		_ = r.ParseForm()
+		w.Header().Set(HeaderContenttype, MimetypeJson)
+		if user != "client" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		n := atomic.AddInt32(&tokenCount, 1)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "read write", r.PostForm.Get("scope"))
+		token := "token1"
+		if n > 1 {
+			token = "token2"
+		}
+		_, _ = w.Write([]byte(`{"access_token":"` + token + `","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	var revoked int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get(HeaderAuthorization)
+		if auth == "Bearer token1" && atomic.LoadInt32(&revoked) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(auth))
+	}))
+	defer server.Close()
+
+	auth := NewOAuth2Auth(&OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	})
+	client := NewClient(Auth(auth))
+	for i := 0; i < 2; i++ {
+		_, body, err := client.Get(server.URL).ReadAllString()
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer token1", body)
+	}
+	assert.Equal(t, int32(1), tokenCount)
+
+	// token revoked by server, fetch new token and retry
+	atomic.StoreInt32(&revoked, 1)
+	_, body, err := client.Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token2", body)
+	assert.Equal(t, int32(2), tokenCount)
+
+	// token endpoint error
+	_, err = NewClient(Auth(NewOAuth2Auth(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "client"}))).
+		Get(server.URL).DiscardBody()
+	var oauthErr *OAuth2Error
+	assert.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, "invalid_client", oauthErr.ErrorCode)
+}
+
+func Test_parseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Digest realm="a \"b\"", qop="auth,auth-int", stale=true, nonce="n"`)
+	assert.Equal(t, "Digest", scheme)
+	assert.Equal(t, map[string]string{"realm": `a "b"`, "qop": "auth,auth-int", "stale": "true", "nonce": "n"}, params)
+}
@@ -25,6 +25,14 @@ type Body interface {
 	GetReader() (io.Reader, error)
 }
 
+// SizedBody is a Body which knows the size of content, before reading it
+type SizedBody interface {
+	Body
+
+	// ContentLength return the byte size of body content, or -1 if unknown
+	ContentLength() int64
+}
+
 type hasMimeType struct {
 	mimeType string
 }
@@ -68,7 +76,7 @@ func NewBodyWithEncoding(reader io.Reader, contentType string, enc encoding.Enco
 	}
 }
 
-var _ Body = (*BytesBody)(nil)
+var _ SizedBody = (*BytesBody)(nil)
 
 // BytesBody is a http body contains byte array as content
 type BytesBody struct {
@@ -99,7 +107,11 @@ func (b *BytesBody) GetReader() (io.Reader, error) {
 	return bytes.NewReader(b.data), nil
 }
 
-var _ Body = (*StringBody)(nil)
+func (b *BytesBody) ContentLength() int64 {
+	return int64(len(b.data))
+}
+
+var _ SizedBody = (*StringBody)(nil)
 
 // StringBody is a http body has string value
 type StringBody struct {
@@ -130,6 +142,13 @@ func (s *StringBody) GetReader() (io.Reader, error) {
 	return s.enc.NewDecoder().Reader(reader), nil
 }
 
+func (s *StringBody) ContentLength() int64 {
+	if s.enc == nil || s.enc == unicode.UTF8 {
+		return int64(len(s.content))
+	}
+	return -1
+}
+
 var _ Body = (*JSONBody)(nil)
 
 // JSONBody is http body, marshal value as json
@@ -197,7 +216,7 @@ func (f *FormBody) GetReader() (io.Reader, error) {
 		return iox.EmptyReader(), nil
 	}
 	var buf strings.Builder
-	err := EncodeParamsTo(buf, f.enc, f.params...)
+	err := WriteParams(&buf, f.enc, f.params...)
 	if err != nil {
 		return nil, err
 	}
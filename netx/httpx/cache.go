@@ -0,0 +1,307 @@
+package httpx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hsiafan/glow/iox"
+)
+
+// the max body size of response can be cached
+const maxCacheBodySize = 10 << 20
+
+// CachedResponse is a response stored in cache
+type CachedResponse struct {
+	StatusCode   int               // the status code
+	Status       string            // the status line, e.g. "200 OK"
+	Header       http.Header       // the response headers
+	Body         []byte            // the response body
+	VaryHeaders  map[string]string // the request header values of headers listed in Vary
+	RequestTime  time.Time         // the time the request was sent
+	ResponseTime time.Time         // the time the response was received
+}
+
+// CacheStorage stores cached responses. Implementations should be safe for concurrent use.
+type CacheStorage interface {
+	// Get return the cached response for key, or nil if not exists
+	Get(key string) *CachedResponse
+	// Set store the response for key
+	Set(key string, resp *CachedResponse)
+	// Delete remove the cached response for key
+	Delete(key string)
+}
+
+// Cache enable http cache for GET requests sent by this client, following RFC 9111 for a private cache:
+// responses are stored according to Cache-Control, Expires and Vary headers, stale responses are revalidated
+// with If-None-Match/If-Modified-Since, and 304 responses are replaced by the cached responses transparently.
+// Requests with Cache-Control: no-store request header, Range header or conditional headers bypass the cache.
+// The cache is implemented as a interceptor, appended to the client interceptors.
+func Cache(storage CacheStorage) ClientOption {
+	return Intercept(newCacheInterceptor(storage))
+}
+
+func newCacheInterceptor(storage CacheStorage) Interceptor {
+	return func(req *http.Request, next Invoker) (*http.Response, error) {
+		key := req.URL.String()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			resp, err := next(req)
+			// unsafe methods invalidate the cache
+			if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+				storage.Delete(key)
+			}
+			return resp, err
+		}
+		requestCC := parseCacheControl(req.Header.Get(HeaderCacheControl))
+		if req.Method != http.MethodGet || requestCC.has("no-store") || req.Header.Get(HeaderRange) != "" ||
+			req.Header.Get(HeaderIfNoneMatch) != "" || req.Header.Get(HeaderIfModifiedSince) != "" {
+			return next(req)
+		}
+
+		cached := storage.Get(key)
+		if cached != nil && !cached.matchVary(req) {
+			cached = nil
+		}
+		if cached != nil {
+			now := time.Now()
+			if !requestCC.has("no-cache") && !cached.cacheControl().has("no-cache") &&
+				cached.age(now) < cached.freshnessLifetime() {
+				return cached.toResponse(req, now), nil
+			}
+			etag := cached.Header.Get(HeaderETag)
+			lastModified := cached.Header.Get(HeaderLastModified)
+			if etag == "" && lastModified == "" {
+				cached = nil
+			} else {
+				req = req.Clone(req.Context())
+				if etag != "" {
+					req.Header.Set(HeaderIfNoneMatch, etag)
+				}
+				if lastModified != "" {
+					req.Header.Set(HeaderIfModifiedSince, lastModified)
+				}
+			}
+		}
+
+		requestTime := time.Now()
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+		responseTime := time.Now()
+		if cached != nil && resp.StatusCode == http.StatusNotModified {
+			iox.Close(resp.Body)
+			// the cached may be shared, update a copy
+			updated := *cached
+			updated.Header = cached.Header.Clone()
+			for name, values := range resp.Header {
+				if name != HeaderContentLength {
+					updated.Header[name] = values
+				}
+			}
+			updated.RequestTime, updated.ResponseTime = requestTime, responseTime
+			storage.Set(key, &updated)
+			return updated.toResponse(req, responseTime), nil
+		}
+		if !isCacheable(resp) {
+			return resp, nil
+		}
+
+		body, complete, err := readLimited(resp.Body, maxCacheBodySize)
+		if err != nil {
+			iox.Close(resp.Body)
+			return nil, err
+		}
+		if !complete {
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return resp, nil
+		}
+		iox.Close(resp.Body)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		varyHeaders := map[string]string{}
+		for _, name := range headerTokens(resp.Header.Values(HeaderVary)) {
+			varyHeaders[http.CanonicalHeaderKey(name)] = req.Header.Get(name)
+		}
+		storage.Set(key, &CachedResponse{
+			StatusCode:   resp.StatusCode,
+			Status:       resp.Status,
+			Header:       resp.Header.Clone(),
+			Body:         body,
+			VaryHeaders:  varyHeaders,
+			RequestTime:  requestTime,
+			ResponseTime: responseTime,
+		})
+		return resp, nil
+	}
+}
+
+// if the request headers match the stored values of Vary headers
+func (c *CachedResponse) matchVary(req *http.Request) bool {
+	for name, value := range c.VaryHeaders {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CachedResponse) cacheControl() cacheControl {
+	return parseCacheControl(c.Header.Get(HeaderCacheControl))
+}
+
+// the current age of response, see RFC 9111 section 4.2.3
+func (c *CachedResponse) age(now time.Time) time.Duration {
+	date, err := ParseDateHeader(c.Header.Get(HeaderDate))
+	if err != nil {
+		date = c.ResponseTime
+	}
+	apparentAge := c.ResponseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	if ageValue, err := strconv.Atoi(c.Header.Get(HeaderAge)); err == nil {
+		if correctedAge := time.Duration(ageValue)*time.Second + c.ResponseTime.Sub(c.RequestTime); correctedAge > apparentAge {
+			apparentAge = correctedAge
+		}
+	}
+	return apparentAge + now.Sub(c.ResponseTime)
+}
+
+// the freshness lifetime of response, see RFC 9111 section 4.2.1
+func (c *CachedResponse) freshnessLifetime() time.Duration {
+	if maxAge, ok := c.cacheControl().seconds("max-age"); ok {
+		return maxAge
+	}
+	date, err := ParseDateHeader(c.Header.Get(HeaderDate))
+	if err != nil {
+		date = c.ResponseTime
+	}
+	if expiresValue := c.Header.Get(HeaderExpires); expiresValue != "" {
+		expires, err := ParseDateHeader(expiresValue)
+		if err != nil {
+			// invalid Expires means already expired
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	// heuristic freshness: 10% of the time since last modified
+	if lastModified, err := ParseDateHeader(c.Header.Get(HeaderLastModified)); err == nil && date.After(lastModified) {
+		return date.Sub(lastModified) / 10
+	}
+	return 0
+}
+
+// create a response from cache
+func (c *CachedResponse) toResponse(req *http.Request, now time.Time) *http.Response {
+	header := c.Header.Clone()
+	header.Set(HeaderAge, strconv.Itoa(int(c.age(now)/time.Second)))
+	return &http.Response{
+		Status:        c.Status,
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// if the response can be stored, see RFC 9111 section 3
+func isCacheable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusGone, http.StatusRequestURITooLong, http.StatusNotImplemented:
+	default:
+		return false
+	}
+	cc := parseCacheControl(resp.Header.Get(HeaderCacheControl))
+	if cc.has("no-store") {
+		return false
+	}
+	for _, name := range headerTokens(resp.Header.Values(HeaderVary)) {
+		if name == "*" {
+			return false
+		}
+	}
+	if strings.HasPrefix(resp.Header.Get(HeaderContenttype), MimeTypeEventStream) {
+		return false
+	}
+	_, hasMaxAge := cc.seconds("max-age")
+	return hasMaxAge || resp.Header.Get(HeaderExpires) != "" ||
+		resp.Header.Get(HeaderETag) != "" || resp.Header.Get(HeaderLastModified) != ""
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// read at most limit bytes. Return if the reader is read to end.
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return data, false, nil
+	}
+	return data, true, nil
+}
+
+// split comma separated header values to tokens
+func headerTokens(values []string) []string {
+	var tokens []string
+	for _, value := range values {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// the Cache-Control directives, names are lower cased
+type cacheControl map[string]string
+
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, directive := range headerTokens([]string{value}) {
+		name, arg := directive, ""
+		if idx := strings.IndexByte(directive, '='); idx >= 0 {
+			name, arg = directive[:idx], strings.Trim(directive[idx+1:], `"`)
+		}
+		cc[strings.ToLower(strings.TrimSpace(name))] = arg
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
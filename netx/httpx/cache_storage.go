@@ -0,0 +1,132 @@
+package httpx
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryCache is a in-memory CacheStorage, evict the least recently used responses when exceeds max entries
+type MemoryCache struct {
+	maxEntries int
+	lock       sync.Mutex
+	lru        *list.List // of *memoryCacheEntry, the front is the most recently used
+	entries    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryCache create a in-memory cache storage, can store at most maxEntries responses.
+// If maxEntries <= 0, the count of responses is unlimited.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Get return the cached response for key, or nil if not exists
+func (m *MemoryCache) Get(key string) *CachedResponse {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if e, ok := m.entries[key]; ok {
+		m.lru.MoveToFront(e)
+		return e.Value.(*memoryCacheEntry).resp
+	}
+	return nil
+}
+
+// Set store the response for key
+func (m *MemoryCache) Set(key string, resp *CachedResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).resp = resp
+		m.lru.MoveToFront(e)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	for m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete remove the cached response for key
+func (m *MemoryCache) Delete(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if e, ok := m.entries[key]; ok {
+		m.lru.Remove(e)
+		delete(m.entries, key)
+	}
+}
+
+// DiskCache is a CacheStorage stores responses as files in a directory.
+// Errors of reading and writing files are ignored, as cache miss.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache create a cache storage stores responses in dir. The dir is created if not exists.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get return the cached response for key, or nil if not exists
+func (d *DiskCache) Get(key string) *CachedResponse {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil
+	}
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil
+	}
+	return &resp
+}
+
+// Set store the response for key
+func (d *DiskCache) Set(key string, resp *CachedResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	// write to temp file then rename, so readers never see partial files
+	f, err := os.CreateTemp(d.dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// Delete remove the cached response for key
+func (d *DiskCache) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
+
+func (d *DiskCache) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(h[:]))
+}
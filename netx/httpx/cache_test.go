@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Cache(t *testing.T) {
+	var count, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set(HeaderCacheControl, "max-age=60")
+			w.Header().Set(HeaderVary, "Accept-Language")
+			_, _ = w.Write([]byte("fresh " + r.Header.Get("Accept-Language")))
+		case "/revalidate":
+			w.Header().Set(HeaderCacheControl, "no-cache")
+			w.Header().Set(HeaderETag, `"v1"`)
+			if r.Header.Get(HeaderIfNoneMatch) == `"v1"` {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = w.Write([]byte("revalidate"))
+		case "/no-store":
+			w.Header().Set(HeaderCacheControl, "no-store, max-age=60")
+			_, _ = w.Write([]byte("no-store"))
+		}
+	}))
+	defer server.Close()
+
+	for _, storage := range []CacheStorage{NewMemoryCache(10), newTestDiskCache(t)} {
+		client := NewClient(Cache(storage))
+		get := func(path string, options ...RequestOption) string {
+			_, body, err := client.Get(server.URL+path, options...).CheckStatus().ReadAllString()
+			assert.NoError(t, err)
+			return body
+		}
+		atomic.StoreInt32(&count, 0)
+		atomic.StoreInt32(&notModified, 0)
+
+		assert.Equal(t, "fresh en", get("/fresh", SetHeader("Accept-Language", "en")))
+		assert.Equal(t, "fresh en", get("/fresh", SetHeader("Accept-Language", "en")))
+		assert.Equal(t, int32(1), count)
+		// vary not match
+		assert.Equal(t, "fresh fr", get("/fresh", SetHeader("Accept-Language", "fr")))
+		assert.Equal(t, int32(2), count)
+		// request no-store bypass cache
+		assert.Equal(t, "fresh fr", get("/fresh", SetHeader("Accept-Language", "fr"), SetHeader(HeaderCacheControl, "no-store")))
+		assert.Equal(t, int32(3), count)
+
+		// unsafe method invalidate cache
+		_, err := client.Post(server.URL+"/fresh", nil).DiscardBody()
+		assert.NoError(t, err)
+		atomic.StoreInt32(&count, 0)
+		assert.Equal(t, "fresh fr", get("/fresh", SetHeader("Accept-Language", "fr")))
+		assert.Equal(t, int32(1), count)
+
+		assert.Equal(t, "revalidate", get("/revalidate"))
+		assert.Equal(t, "revalidate", get("/revalidate"))
+		assert.Equal(t, "revalidate", get("/revalidate"))
+		assert.Equal(t, int32(2), notModified)
+
+		atomic.StoreInt32(&count, 0)
+		assert.Equal(t, "no-store", get("/no-store"))
+		assert.Equal(t, "no-store", get("/no-store"))
+		assert.Equal(t, int32(2), count)
+	}
+}
+
+func newTestDiskCache(t *testing.T) *DiskCache {
+	cache, err := NewDiskCache(t.TempDir())
+	assert.NoError(t, err)
+	return cache
+}
+
+func TestCachedResponse_freshnessLifetime(t *testing.T) {
+	now := time.Now()
+	resp := &CachedResponse{Header: http.Header{}, ResponseTime: now}
+	resp.Header.Set(HeaderDate, FormatDateHeader(now))
+	assert.Equal(t, time.Duration(0), resp.freshnessLifetime())
+
+	resp.Header.Set(HeaderLastModified, FormatDateHeader(now.Add(-10*time.Hour)))
+	assert.Equal(t, time.Hour, resp.freshnessLifetime())
+
+	resp.Header.Set(HeaderExpires, FormatDateHeader(now.Add(time.Minute)))
+	assert.Equal(t, time.Minute, resp.freshnessLifetime())
+
+	resp.Header.Set(HeaderCacheControl, "public, max-age=10")
+	assert.Equal(t, 10*time.Second, resp.freshnessLifetime())
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", &CachedResponse{Status: "a"})
+	cache.Set("b", &CachedResponse{Status: "b"})
+	assert.NotNil(t, cache.Get("a"))
+	cache.Set("c", &CachedResponse{Status: "c"})
+	assert.Nil(t, cache.Get("b"))
+	assert.Equal(t, "a", cache.Get("a").Status)
+	cache.Delete("a")
+	assert.Nil(t, cache.Get("a"))
+}
+
+func TestMemoryCache_unlimited(t *testing.T) {
+	cache := NewMemoryCache(0)
+	cache.Set("a", &CachedResponse{Status: "a"})
+	cache.Set("b", &CachedResponse{Status: "b"})
+	assert.Equal(t, "a", cache.Get("a").Status)
+	assert.Equal(t, "b", cache.Get("b").Status)
+}
@@ -0,0 +1,444 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hsiafan/glow/hashx"
+	"github.com/hsiafan/glow/iox"
+	"github.com/hsiafan/glow/timex/durationx"
+)
+
+// DownloadOptions is the options for Client.Download
+type DownloadOptions struct {
+	// Parallel is the count of ranged chunks downloaded concurrently. If <= 1, or server not support range requests,
+	// download by one connection.
+	Parallel int
+	// Progress is called when data is written, with the downloaded bytes and total bytes(-1 if unknown).
+	// Calls are serialized.
+	Progress func(downloaded int64, total int64)
+	// Hash and Digest set the expected hex digest of file, such as Hash: sha256.New, Digest: "9f86d08...".
+	// If either is not set, the file is not verified.
+	Hash   func() hash.Hash
+	Digest string
+	// RequestOptions are applied to every request
+	RequestOptions []RequestOption
+}
+
+// the suffixes of temp file and its meta file, for downloading
+const (
+	downloadTempSuffix = ".part"
+	downloadMetaSuffix = ".part.meta"
+)
+
+// the interval of saving download progress to meta file
+var downloadSaveInterval = durationx.Seconds(1)
+
+// ErrResourceChanged is returned by Client.Download, if the resource changed while downloading,
+// and also changed when retried from the beginning.
+var ErrResourceChanged = errors.New("resource changed")
+
+// Download download url content to file path. The data is written to a temp file path.part, and renamed to path
+// when finished and verified.
+// If a former download of the same path is interrupted, it resumes by range requests, if server support and the
+// resource not changed(checked by If-Range with ETag or Last-Modified).
+// The opts can be nil.
+func (c *Client) Download(url string, path string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	d := &downloader{client: c, url: url, path: path, opts: opts}
+	err := d.download()
+	if err == ErrResourceChanged {
+		d.removeTemp()
+		err = d.download()
+	}
+	if err == ErrResourceChanged {
+		return fmt.Errorf("%w: %v, validator %v", err, url, d.meta.Validator)
+	}
+	return err
+}
+
+// the state of a download, saved to meta file for resuming
+type downloadMeta struct {
+	Validator string           // the ETag or Last-Modified of resource
+	Size      int64            // the size of resource, -1 if unknown
+	Chunks    []*downloadChunk // the chunks
+}
+
+type downloadChunk struct {
+	Start int64 // the start offset
+	End   int64 // the end offset, inclusive. -1 means to the end of resource
+	Done  int64 // the bytes downloaded
+}
+
+func (ch *downloadChunk) finished() bool {
+	return ch.End >= 0 && ch.Start+ch.Done > ch.End
+}
+
+type downloader struct {
+	client *Client
+	url    string
+	path   string
+	opts   *DownloadOptions
+
+	lock       sync.Mutex // guard meta and progress calls
+	meta       *downloadMeta
+	downloaded int64
+	lastSave   time.Time // the last time progress saved
+
+	saveLock sync.Mutex // serialize writing meta file
+}
+
+func (d *downloader) download() error {
+	tempPath := d.path + downloadTempSuffix
+	meta, err := d.loadMeta(tempPath)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		if meta, err = d.newMeta(); err != nil {
+			return err
+		}
+	}
+	d.meta = meta
+	d.lastSave = time.Now()
+	d.downloaded = 0
+	for _, ch := range meta.Chunks {
+		d.downloaded += ch.Done
+	}
+
+	f, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	err = d.downloadChunks(f)
+	if sErr := d.saveMeta(f); err == nil {
+		err = sErr
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if meta.Size >= 0 {
+		info, err := os.Stat(tempPath)
+		if err != nil {
+			return err
+		}
+		if info.Size() != meta.Size {
+			return fmt.Errorf("download size mismatch, expect %d, got %d", meta.Size, info.Size())
+		}
+	}
+	if d.opts.Hash != nil && d.opts.Digest != "" {
+		result, err := hashx.HashFile(tempPath, d.opts.Hash)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(result.ToHex(), d.opts.Digest) {
+			d.removeTemp()
+			return fmt.Errorf("download digest mismatch, expect %v, got %v", d.opts.Digest, result.ToHex())
+		}
+	}
+	if err := os.Rename(tempPath, d.path); err != nil {
+		return err
+	}
+	_ = os.Remove(d.path + downloadMetaSuffix)
+	return nil
+}
+
+// download all unfinished chunks
+func (d *downloader) downloadChunks(f *os.File) error {
+	var chunks []*downloadChunk
+	for _, ch := range d.meta.Chunks {
+		if !ch.finished() {
+			chunks = append(chunks, ch)
+		}
+	}
+	if len(chunks) == 1 {
+		return d.downloadChunk(f, chunks[0], nil)
+	}
+	// cancel other chunks when one failed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for _, ch := range chunks {
+		wg.Add(1)
+		go func(ch *downloadChunk) {
+			defer wg.Done()
+			if err := d.downloadChunk(f, ch, ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(ch)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// download a chunk. If cancelCtx is not nil, the request is also canceled when cancelCtx is done.
+func (d *downloader) downloadChunk(f *os.File, ch *downloadChunk, cancelCtx context.Context) error {
+	// disable transparent compression, to make the offsets of written data match the ranges
+	options := append([]RequestOption{SetHeader(HeaderAcceptEncoding, "identity"), withoutCheckStatus()},
+		d.opts.RequestOptions...)
+	if cancelCtx != nil {
+		options = append(options, withCancelContext(cancelCtx))
+	}
+	wholeFile := len(d.meta.Chunks) == 1 && ch.Done == 0
+	if !wholeFile {
+		rangeValue := "bytes=" + strconv.FormatInt(ch.Start+ch.Done, 10) + "-"
+		if ch.End >= 0 {
+			rangeValue += strconv.FormatInt(ch.End, 10)
+		}
+		options = append(options, SetHeader(HeaderRange, rangeValue), SetHeader(HeaderIfRange, d.meta.Validator))
+	}
+	holder := d.client.Get(d.url, options...)
+	if holder.Err != nil {
+		return holder.Err
+	}
+	resp := holder.Response
+	defer iox.Close(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if len(d.meta.Chunks) > 1 {
+			return ErrResourceChanged
+		}
+		// not support range, or resource changed; download from the beginning
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		d.lock.Lock()
+		d.downloaded -= ch.Done
+		ch.Done = 0
+		d.meta.Validator = headerValidator(resp.Header)
+		d.meta.Size = resp.ContentLength
+		if d.meta.Size < 0 {
+			d.meta.Size = -1
+		}
+		d.lock.Unlock()
+		if err := d.saveMeta(f); err != nil {
+			return err
+		}
+	case http.StatusPartialContent:
+		start, _, size, ok := parseContentRange(resp.Header.Get(HeaderContentRange))
+		if !ok || start != ch.Start+ch.Done || (d.meta.Size >= 0 && size >= 0 && size != d.meta.Size) {
+			return ErrResourceChanged
+		}
+		if d.meta.Size < 0 && size >= 0 {
+			d.lock.Lock()
+			d.meta.Size = size
+			d.lock.Unlock()
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the resumed download may have finished
+		size, ok := parseUnsatisfiedRange(resp.Header.Get(HeaderContentRange))
+		if ok && ch.End < 0 && size == ch.Start+ch.Done {
+			d.lock.Lock()
+			d.meta.Size = size
+			d.lock.Unlock()
+			return nil
+		}
+		return ErrResourceChanged
+	default:
+		return holder.readStatusError()
+	}
+
+	w := &downloadWriter{d: d, f: f, ch: ch}
+	_, err := io.Copy(w, resp.Body)
+	if err == nil {
+		err = d.saveMeta(f)
+	}
+	return err
+}
+
+// write to file at chunk offset, and update progress
+type downloadWriter struct {
+	d  *downloader
+	f  *os.File
+	ch *downloadChunk
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	d, ch := w.d, w.ch
+	if ch.End >= 0 && ch.Start+ch.Done+int64(len(p)) > ch.End+1 {
+		return 0, errors.New("server returned more data than requested")
+	}
+	n, err := w.f.WriteAt(p, ch.Start+ch.Done)
+	d.lock.Lock()
+	ch.Done += int64(n)
+	d.downloaded += int64(n)
+	if d.opts.Progress != nil {
+		d.opts.Progress(d.downloaded, d.meta.Size)
+	}
+	save := time.Since(d.lastSave) >= downloadSaveInterval
+	if save {
+		d.lastSave = time.Now()
+	}
+	d.lock.Unlock()
+	if err == nil && save {
+		// save progress regularly, so partial chunks can be resumed if the process is killed
+		err = d.saveMeta(w.f)
+	}
+	return n, err
+}
+
+// create meta for a new download. If parallel, send a HEAD request to get size and check range support.
+func (d *downloader) newMeta() (*downloadMeta, error) {
+	meta := &downloadMeta{Size: -1, Chunks: []*downloadChunk{{Start: 0, End: -1}}}
+	if d.opts.Parallel <= 1 {
+		return meta, nil
+	}
+	options := append([]RequestOption{withoutCheckStatus()}, d.opts.RequestOptions...)
+	header, err := d.client.Head(d.url, options...).DiscardBody()
+	if err != nil {
+		return nil, err
+	}
+	if header.StatusCode != http.StatusOK {
+		return meta, nil
+	}
+	size, _ := strconv.ParseInt(header.Header.Get(HeaderContentLength), 10, 64)
+	validator := headerValidator(header.Header)
+	if header.Header.Get(HeaderAcceptRanges) != "bytes" || size <= 0 || validator == "" {
+		return meta, nil
+	}
+	meta.Size = size
+	meta.Validator = validator
+	meta.Chunks = nil
+	chunkSize := (size + int64(d.opts.Parallel) - 1) / int64(d.opts.Parallel)
+	for start := int64(0); start < size; start += chunkSize {
+		end := start + chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		meta.Chunks = append(meta.Chunks, &downloadChunk{Start: start, End: end})
+	}
+	return meta, nil
+}
+
+// load meta of interrupted download. Return nil if not exists or can not resume.
+func (d *downloader) loadMeta(tempPath string) (*downloadMeta, error) {
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, err := os.ReadFile(d.path + downloadMetaSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta downloadMeta
+	if err := json.Unmarshal(data, &meta); err != nil || meta.Validator == "" || len(meta.Chunks) == 0 {
+		return nil, nil
+	}
+	if len(meta.Chunks) == 1 {
+		// for single connection, the data is written sequentially
+		meta.Chunks[0].Done = info.Size()
+	}
+	return &meta, nil
+}
+
+// save meta after syncing the temp file, so the meta never records data not on disk
+func (d *downloader) saveMeta(f *os.File) error {
+	d.saveLock.Lock()
+	defer d.saveLock.Unlock()
+	d.lock.Lock()
+	data, err := json.Marshal(d.meta)
+	d.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	metaPath := d.path + downloadMetaSuffix
+	if err := os.WriteFile(metaPath+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(metaPath+".tmp", metaPath)
+}
+
+// derive the request context, which is also canceled when ctx is done
+func withCancelContext(ctx context.Context) RequestOption {
+	return func(r *http.Request) (error, *http.Request) {
+		reqCtx, cancel := context.WithCancel(r.Context())
+		go func() {
+			defer cancel()
+			select {
+			case <-ctx.Done():
+			case <-reqCtx.Done():
+			}
+		}()
+		return nil, r.WithContext(reqCtx)
+	}
+}
+
+func (d *downloader) removeTemp() {
+	_ = os.Remove(d.path + downloadTempSuffix)
+	_ = os.Remove(d.path + downloadMetaSuffix)
+}
+
+// the validator for If-Range. Weak ETag can not be used.
+func headerValidator(header http.Header) string {
+	if etag := header.Get(HeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get(HeaderLastModified)
+}
+
+// parse Content-Range header value of 416 response, like "bytes */200"
+func parseUnsatisfiedRange(value string) (int64, bool) {
+	if !strings.HasPrefix(value, "bytes */") {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(strings.TrimPrefix(value, "bytes */"), 10, 64)
+	return size, err == nil
+}
+
+// parse Content-Range header value like "bytes 0-99/200", size is -1 if is "*"
+func parseContentRange(value string) (start int64, end int64, size int64, ok bool) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, 0, false
+	}
+	value = strings.TrimPrefix(value, "bytes ")
+	slash := strings.IndexByte(value, '/')
+	dash := strings.IndexByte(value, '-')
+	if slash < 0 || dash < 0 || dash > slash {
+		return 0, 0, 0, false
+	}
+	var err error
+	if start, err = strconv.ParseInt(value[:dash], 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	if end, err = strconv.ParseInt(value[dash+1:slash], 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	size = -1
+	if value[slash+1:] != "*" {
+		if size, err = strconv.ParseInt(value[slash+1:], 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	return start, end, size, true
+}
@@ -0,0 +1,238 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hsiafan/glow/hashx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Download(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10000))
+	digest := hashx.HashBytes(content, sha256.New).ToHex()
+	var lock sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		ranges = append(ranges, r.Method+" "+r.Header.Get(HeaderRange))
+		lock.Unlock()
+		w.Header().Set(HeaderETag, `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	client := NewClient()
+
+	path := filepath.Join(dir, "file")
+	var downloaded, total int64
+	err := client.Download(server.URL, path, &DownloadOptions{
+		Hash:   sha256.New,
+		Digest: digest,
+		Progress: func(d int64, t int64) {
+			downloaded, total = d, t
+		},
+	})
+	assert.NoError(t, err)
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, content, data)
+	assert.Equal(t, int64(len(content)), downloaded)
+	assert.Equal(t, int64(len(content)), total)
+	_, err = os.Stat(path + downloadTempSuffix)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + downloadMetaSuffix)
+	assert.True(t, os.IsNotExist(err))
+
+	// parallel
+	ranges = nil
+	path = filepath.Join(dir, "parallel")
+	assert.NoError(t, client.Download(server.URL, path, &DownloadOptions{Parallel: 4, Hash: sha256.New, Digest: digest}))
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, content, data)
+	assert.Equal(t, 5, len(ranges))
+	assert.Contains(t, ranges, "GET bytes=75000-99999")
+
+	// digest mismatch
+	path = filepath.Join(dir, "mismatch")
+	assert.Error(t, client.Download(server.URL, path, &DownloadOptions{Hash: sha256.New, Digest: "abc"}))
+	_, err = os.Stat(path + downloadTempSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestClient_Download_resume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+	var lock sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		ranges = append(ranges, r.Method+" "+r.Header.Get(HeaderRange))
+		lock.Unlock()
+		w.Header().Set(HeaderETag, `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	client := NewClient()
+
+	writePart := func(path string, etag string) {
+		assert.NoError(t, os.WriteFile(path+downloadTempSuffix, content[:3000], 0644))
+		meta, _ := json.Marshal(&downloadMeta{Validator: etag, Size: -1, Chunks: []*downloadChunk{{End: -1}}})
+		assert.NoError(t, os.WriteFile(path+downloadMetaSuffix, meta, 0644))
+	}
+
+	path := filepath.Join(dir, "resume")
+	writePart(path, `"v1"`)
+	assert.NoError(t, client.Download(server.URL, path, nil))
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, content, data)
+	assert.Equal(t, []string{"GET bytes=3000-"}, ranges)
+
+	// the resource changed, download from the beginning
+	ranges = nil
+	path = filepath.Join(dir, "changed")
+	writePart(path, `"v0"`)
+	assert.NoError(t, client.Download(server.URL, path, nil))
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, content, data)
+
+	// parallel download interrupted, resume the unfinished chunks
+	ranges = nil
+	path = filepath.Join(dir, "chunks")
+	assert.NoError(t, os.WriteFile(path+downloadTempSuffix, append(content[:5000:5000], make([]byte, 5000)...), 0644))
+	meta, _ := json.Marshal(&downloadMeta{Validator: `"v1"`, Size: int64(len(content)), Chunks: []*downloadChunk{
+		{Start: 0, End: 4999, Done: 5000},
+		{Start: 5000, End: 9999, Done: 0},
+	}})
+	assert.NoError(t, os.WriteFile(path+downloadMetaSuffix, meta, 0644))
+	assert.NoError(t, client.Download(server.URL, path, nil))
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, content, data)
+	assert.Equal(t, []string{"GET bytes=5000-9999"}, ranges)
+}
+
+func TestClient_Download_options(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10000))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderETag, `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	// no digest to verify
+	path := filepath.Join(dir, "nodigest")
+	assert.NoError(t, NewClient().Download(server.URL, path, &DownloadOptions{Hash: sha256.New}))
+
+	// the resumed download has finished, and server response 416
+	client := NewClient(CheckStatus())
+	path = filepath.Join(dir, "finished")
+	assert.NoError(t, os.WriteFile(path+downloadTempSuffix, content, 0644))
+	meta, _ := json.Marshal(&downloadMeta{Validator: `"v1"`, Size: -1, Chunks: []*downloadChunk{{End: -1}}})
+	assert.NoError(t, os.WriteFile(path+downloadMetaSuffix, meta, 0644))
+	assert.NoError(t, client.Download(server.URL, path, nil))
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, content, data)
+}
+
+func TestClient_Download_saveProgress(t *testing.T) {
+	interval := downloadSaveInterval
+	downloadSaveInterval = 0
+	defer func() {
+		downloadSaveInterval = interval
+	}()
+	content := []byte(strings.Repeat("0123456789", 100000))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderETag, `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file")
+	var savedDone int64
+	err := NewClient().Download(server.URL, path, &DownloadOptions{
+		Parallel: 2,
+		Progress: func(downloaded int64, total int64) {
+			data, err := os.ReadFile(path + downloadMetaSuffix)
+			if err != nil {
+				return
+			}
+			var meta downloadMeta
+			assert.NoError(t, json.Unmarshal(data, &meta))
+			var done int64
+			for _, ch := range meta.Chunks {
+				done += ch.Done
+			}
+			if done < total && done > savedDone {
+				savedDone = done
+			}
+		},
+	})
+	assert.NoError(t, err)
+	// progress of unfinished chunks is saved
+	assert.True(t, savedDone > 0)
+}
+
+func Test_parseContentRange(t *testing.T) {
+	start, end, size, ok := parseContentRange("bytes 0-99/200")
+	assert.True(t, ok)
+	assert.Equal(t, []int64{0, 99, 200}, []int64{start, end, size})
+	_, _, size, ok = parseContentRange("bytes 10-99/*")
+	assert.True(t, ok)
+	assert.Equal(t, int64(-1), size)
+	_, _, _, ok = parseContentRange("bytes */200")
+	assert.False(t, ok)
+}
+
+func TestClient_Download_errors(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	// the server ignores ranges, and the resource can not be downloaded in parallel
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderETag, `"v1"`)
+		w.Header().Set(HeaderAcceptRanges, "bytes")
+		w.Header().Set(HeaderContentLength, strconv.Itoa(len(content)))
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+	err := NewClient().Download(server.URL, filepath.Join(t.TempDir(), "changed"), &DownloadOptions{Parallel: 2})
+	assert.True(t, errors.Is(err, ErrResourceChanged), err)
+	assert.Contains(t, err.Error(), server.URL)
+
+	// the failed chunk cancels other chunks
+	canceled := make(chan struct{})
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderETag, `"v1"`)
+		switch {
+		case r.Method == http.MethodHead:
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		case strings.HasPrefix(r.Header.Get(HeaderRange), "bytes=0-"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			select {
+			case <-r.Context().Done():
+				close(canceled)
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+	err = NewClient().Download(server.URL, filepath.Join(t.TempDir(), "failed"), &DownloadOptions{Parallel: 2})
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr), err)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the other chunk is not canceled")
+	}
+}
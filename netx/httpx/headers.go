@@ -61,6 +61,7 @@ const (
 	HeaderRange                         string = "Range"
 	HeaderReferer                       string = "Referer"
 	HeaderRetryAfter                    string = "Retry-After"
+	HeaderSecWebsocketAccept            string = "Sec-Websocket-Accept"
 	HeaderSecWebsocketExtensions        string = "Sec-Websocket-Extensions"
 	HeaderSecWebsocketKey               string = "Sec-Websocket-Key"
 	HeaderSecWebsocketOrigin            string = "Sec-Websocket-Origin"
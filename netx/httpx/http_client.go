@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptrace"
 	"net/url"
 	"time"
 
@@ -16,11 +17,17 @@ import (
 
 // Client means Http Client
 type Client struct {
-	client    *http.Client
-	dialer    *net.Dialer
-	transport *http.Transport
-	tlsConfig *tls.Config
-	userAgent string
+	client       *http.Client
+	dialer       *net.Dialer
+	transport    *http.Transport
+	tlsConfig    *tls.Config
+	userAgent    string
+	retryPolicy  *RetryPolicy  // may be nil
+	interceptors []Interceptor // called before sending every request
+	checkStatus  bool          // if check the response status is 2xx
+	trace        bool          // if record timings for requests
+	proxy        ProxyRule     // may be nil
+	proxyUser    *url.Userinfo // may be nil
 }
 
 // NewClient create new http client
@@ -45,6 +52,7 @@ func NewClient(options ...ClientOption) *Client {
 			Timeout:   durationx.Minutes(2),
 		},
 	}
+	transport.Proxy = client.proxyURL
 	for _, option := range options {
 		option(client)
 	}
@@ -54,76 +62,84 @@ func NewClient(options ...ClientOption) *Client {
 
 // Head send a head request
 func (c *Client) Head(url string, options ...RequestOption) *ResponseHolder {
-	req, err := http.NewRequest("HEAD", url, nil)
-	if err != nil {
-		return &ResponseHolder{Err: err}
-	}
-	return c.Send(req, options...)
+	return c.Request(http.MethodHead, url, nil, options...)
 }
 
 // Get send a get request
 func (c *Client) Get(url string, options ...RequestOption) *ResponseHolder {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return &ResponseHolder{Err: err}
-	}
-	return c.Send(req, options...)
+	return c.Request(http.MethodGet, url, nil, options...)
 }
 
 // Delete send a delete request
 func (c *Client) Delete(url string, options ...RequestOption) *ResponseHolder {
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return &ResponseHolder{Err: err}
-	}
-	return c.Send(req, options...)
+	return c.Request(http.MethodDelete, url, nil, options...)
+}
+
+// Options send a options request
+func (c *Client) Options(url string, options ...RequestOption) *ResponseHolder {
+	return c.Request(http.MethodOptions, url, nil, options...)
 }
 
 // Put put send a put request with body
 func (c *Client) Put(url string, body Body, options ...RequestOption) *ResponseHolder {
-	if body == nil {
-		body = EmptyBody()
-	}
-	reader, err := body.GetReader()
-	if err != nil {
-		return &ResponseHolder{Err: err}
-	}
-	req, err := http.NewRequest("PUT", url, reader)
-	if err != nil {
-		return &ResponseHolder{Err: err}
-	}
+	return c.Request(http.MethodPut, url, body, options...)
+}
 
-	contentType, err := c.makeContentType(body.MimeType(), body.Encoding())
+// Post send a post request with body
+func (c *Client) Post(url string, body Body, options ...RequestOption) *ResponseHolder {
+	return c.Request(http.MethodPost, url, body, options...)
+}
+
+// Patch send a patch request with body
+func (c *Client) Patch(url string, body Body, options ...RequestOption) *ResponseHolder {
+	return c.Request(http.MethodPatch, url, body, options...)
+}
+
+// Request send a request with method and body. The body can be nil.
+func (c *Client) Request(method string, url string, body Body, options ...RequestOption) *ResponseHolder {
+	req, err := c.newRequest(method, url, body)
 	if err != nil {
 		return &ResponseHolder{Err: err}
 	}
-	if contentType != "" {
-		req.Header.Set(HeaderContenttype, contentType)
-	}
 	return c.Send(req, options...)
 }
 
-// Post send a post request with body
-func (c *Client) Post(url string, body Body, options ...RequestOption) *ResponseHolder {
+// create a http request with body, and set the content type header.
+// If body implements SizedBody, the Content-Length is set; If body can be read multi times,
+// the GetBody of request is set, for re-sending on redirects and retries.
+func (c *Client) newRequest(method string, url string, body Body) (*http.Request, error) {
 	if body == nil {
 		body = EmptyBody()
 	}
 	reader, err := body.GetReader()
 	if err != nil {
-		return &ResponseHolder{Err: err}
+		return nil, err
 	}
-	req, err := http.NewRequest("POST", url, reader)
+	req, err := http.NewRequest(method, url, reader)
 	if err != nil {
-		return &ResponseHolder{Err: err}
+		return nil, err
 	}
 	contentType, err := c.makeContentType(body.MimeType(), body.Encoding())
 	if err != nil {
-		return &ResponseHolder{Err: err}
+		return nil, err
 	}
 	if contentType != "" {
 		req.Header.Set(HeaderContenttype, contentType)
 	}
-	return c.Send(req, options...)
+	if reader == nil {
+		return req, nil
+	}
+	if sized, ok := body.(SizedBody); ok {
+		if size := sized.ContentLength(); size == 0 {
+			req.Body = http.NoBody
+		} else if size > 0 {
+			req.ContentLength = size
+		}
+	}
+	if getBody := bodyGetter(body); getBody != nil {
+		req.GetBody = getBody
+	}
+	return req, nil
 }
 
 func (c *Client) makeContentType(contentType string, encoding encoding.Encoding) (string, error) {
@@ -152,8 +168,38 @@ func (c *Client) Send(r *http.Request, options ...RequestOption) *ResponseHolder
 			return &ResponseHolder{Err: err}
 		}
 	}
-	resp, err := c.client.Do(r)
-	return &ResponseHolder{resp, err}
+	var tracer *timingsTracer
+	if c.traceEnabled(r) {
+		tracer = newTimingsTracer()
+		r = r.WithContext(httptrace.WithClientTrace(r.Context(), tracer.clientTrace()))
+	}
+	resp, err := c.do(r, tracer)
+	holder := &ResponseHolder{Response: resp, Err: err}
+	if tracer != nil {
+		holder.Timings = tracer.finish()
+	}
+	if c.checkStatus && r.Context().Value(noCheckStatusKey{}) == nil {
+		holder.CheckStatus()
+	}
+	return holder
+}
+
+// send the request through interceptors, retry if retry policy is set. The tracer may be nil.
+func (c *Client) do(r *http.Request, tracer *timingsTracer) (*http.Response, error) {
+	invoker := c.invoker(r)
+	if tracer != nil {
+		next := invoker
+		invoker = func(r *http.Request) (*http.Response, error) {
+			// only record timings of the last attempt
+			tracer.reset()
+			return next(r)
+		}
+	}
+	policy := c.getRetryPolicy(r)
+	if policy == nil {
+		return invoker(r)
+	}
+	return policy.execute(r, invoker)
 }
 
 // ClientOption for setting http client option.
@@ -270,15 +316,20 @@ func UserAgent(userAgent string) ClientOption {
 	}
 }
 
+// UseTransport set the RoundTripper used to send requests, such as a mock transport for testing.
+// The dialer, tls and proxy options of this client have no effect on the transport.
+func UseTransport(transport http.RoundTripper) ClientOption {
+	return func(client *Client) {
+		client.client.Transport = transport
+	}
+}
+
 // UseProxy set proxy by proxy url.
 // The proxy type is determined by the URL scheme. "http", "https", and "socks5" are supported.
-// If the scheme is empty, "http" is assumed.
-// If Proxy url parse error, no proxy is used.
+// If the scheme is empty, "http" is assumed. The proxy url may contain username and password.
+// If Proxy url parse error, requests fail with the error.
 func UseProxy(proxy string) ClientOption {
-	return func(client *Client) {
-		_url, err := url.Parse(proxy)
-		client.transport.Proxy = func(request *http.Request) (*url.URL, error) {
-			return _url, err
-		}
-	}
+	return UseProxyRule(func(target *url.URL) string {
+		return proxy
+	})
 }
@@ -0,0 +1,303 @@
+package httpxtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hsiafan/glow/iox"
+)
+
+// Mode is the mode of Recorder
+type Mode int
+
+const (
+	// ModeReplay replay interactions from cassette file, fail if no interaction matches
+	ModeReplay Mode = iota
+	// ModeRecord send requests by real transport, and record interactions to cassette file
+	ModeRecord
+	// ModeAuto replay if cassette file exists, else record
+	ModeAuto
+)
+
+// Cassette holds recorded interactions
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request/response pair
+type Interaction struct {
+	Request  *RecordedRequest  `json:"request"`
+	Response *RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded http request
+type RecordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body"`
+}
+
+// RecordedResponse is a recorded http response
+type RecordedResponse struct {
+	StatusCode int          `json:"status_code"`
+	Status     string       `json:"status"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       RecordedBody `json:"body"`
+}
+
+// RecordedBody is the body of recorded request or response. Text body is stored as is, binary body is stored as base64.
+type RecordedBody []byte
+
+// MarshalJSON marshal body to json string
+func (b RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(map[string]string{"text": string(b)})
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON unmarshal body from json
+func (b *RecordedBody) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if encoded, ok := m["base64"]; ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		*b = decoded
+		return err
+	}
+	*b = RecordedBody(m["text"])
+	return nil
+}
+
+// Matcher check if a request matches a recorded request
+type Matcher func(r *http.Request, body []byte, recorded *RecordedRequest) bool
+
+// MatchMethod match request method
+func MatchMethod(r *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return r.Method == recorded.Method
+}
+
+// MatchURL match the full request url
+func MatchURL(r *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return r.URL.String() == recorded.URL
+}
+
+// MatchBody match the request body
+func MatchBody(r *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return bytes.Equal(body, recorded.Body)
+}
+
+// MatchHeader return a matcher match the request header value
+func MatchHeader(name string) Matcher {
+	return func(r *http.Request, body []byte, recorded *RecordedRequest) bool {
+		return r.Header.Get(name) == recorded.Header.Get(name)
+	}
+}
+
+// Recorder is a http.RoundTripper, record interactions to a cassette file, or replay them.
+// Use it by httpx.UseTransport(recorder).
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matchers  []Matcher
+	redacted  []string
+
+	lock     sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// RecorderOption is option for Recorder
+type RecorderOption func(r *Recorder)
+
+// WithMatchers set the matchers for replaying. Default matchers are MatchMethod and MatchURL.
+func WithMatchers(matchers ...Matcher) RecorderOption {
+	return func(r *Recorder) {
+		r.matchers = matchers
+	}
+}
+
+// WithTransport set the real transport for recording. Default is http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// RedactHeaders set request and response headers not recorded, such as Authorization.
+func RedactHeaders(names ...string) RecorderOption {
+	return func(r *Recorder) {
+		r.redacted = append(r.redacted, names...)
+	}
+}
+
+// NewRecorder create a Recorder using cassette file path.
+// For replay mode, the cassette file is loaded. For record mode, interactions are saved when call Close.
+func NewRecorder(path string, mode Mode, options ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		matchers:  []Matcher{MatchMethod, MatchURL},
+		cassette:  &Cassette{},
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, r.cassette); err != nil {
+			return nil, fmt.Errorf("invalid cassette file %v: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Mode return the actual mode, ModeAuto is resolved to ModeReplay or ModeRecord
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RoundTrip record or replay the request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		iox.Close(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.match(req, body, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		recorded := interaction.Response
+		return &http.Response{
+			Status:        recorded.Status,
+			StatusCode:    recorded.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.New("httpxtest: no recorded interaction for " + req.Method + " " + req.URL.String())
+}
+
+func (r *Recorder) match(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	for _, matcher := range r.matchers {
+		if !matcher(req, body, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	realReq := req.Clone(req.Context())
+	if body != nil {
+		realReq.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.transport.RoundTrip(realReq)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	iox.Close(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: &RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redact(req.Header),
+			Body:   body,
+		},
+		Response: &RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     r.redact(resp.Header),
+			Body:       respBody,
+		},
+	}
+	r.lock.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.lock.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range r.redacted {
+		header.Del(name)
+	}
+	return header
+}
+
+// Close save the cassette file if recording.
+func (r *Recorder) Close() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.lock.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+// Unused return the recorded interactions not replayed, as "METHOD URL" strings
+func (r *Recorder) Unused() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var unused []string
+	for i, interaction := range r.cassette.Interactions {
+		if i < len(r.used) && !r.used[i] {
+			unused = append(unused, strings.TrimSpace(interaction.Request.Method+" "+interaction.Request.URL))
+		}
+	}
+	return unused
+}
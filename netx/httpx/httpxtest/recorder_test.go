@@ -0,0 +1,49 @@
+package httpxtest
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/hsiafan/glow/netx/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	server := NewServer(t)
+	server.When(GET("/users/1")).Reply(http.StatusOK, Text("user 1")).Times(1)
+	server.When(POST("/upload")).Reply(http.StatusOK, httpx.NewBytesBody([]byte{0xff, 0x00}, httpx.MimeTypeOctetStream)).Times(1)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// record
+	recorder, err := NewRecorder(path, ModeAuto, RedactHeaders(httpx.HeaderAuthorization))
+	assert.NoError(t, err)
+	assert.Equal(t, ModeRecord, recorder.Mode())
+	client := httpx.NewClient(httpx.UseTransport(recorder))
+	_, body, err := client.Get(server.URL+"/users/1", httpx.SetHeader(httpx.HeaderAuthorization, "secret")).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "user 1", body)
+	_, data, err := client.Post(server.URL+"/upload", httpx.NewStringBody("a", httpx.MimeTypePlainText)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, data)
+	assert.NoError(t, recorder.Close())
+	server.Verify()
+
+	// replay, without server
+	recorder, err = NewRecorder(path, ModeAuto, WithMatchers(MatchMethod, MatchURL, MatchBody))
+	assert.NoError(t, err)
+	assert.Equal(t, ModeReplay, recorder.Mode())
+	client = httpx.NewClient(httpx.UseTransport(recorder))
+	_, data, err = client.Post(server.URL+"/upload", httpx.NewStringBody("a", httpx.MimeTypePlainText)).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, data)
+	assert.Equal(t, []string{"GET " + server.URL + "/users/1"}, recorder.Unused())
+
+	_, _, err = client.Post(server.URL+"/upload", httpx.NewStringBody("b", httpx.MimeTypePlainText)).ReadAll()
+	assert.Error(t, err)
+	header, body, err := client.Get(server.URL + "/users/1").ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "user 1", body)
+	assert.Equal(t, http.StatusOK, header.StatusCode)
+	assert.Empty(t, recorder.Unused())
+}
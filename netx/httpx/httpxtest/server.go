@@ -0,0 +1,253 @@
+package httpxtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hsiafan/glow/iox"
+	"github.com/hsiafan/glow/netx/httpx"
+)
+
+// Server is a stub http server for testing. Register stubs by When, then send requests to Server.URL.
+// Requests match no stubs get 404 responses, and are reported by Verify.
+type Server struct {
+	*httptest.Server
+	t testing.TB
+
+	lock      sync.Mutex
+	stubs     []*Stub
+	unmatched []*RecordedRequest
+}
+
+// NewServer start a stub server, which is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// When register a stub for requests match the pattern. Later registered stubs have higher priority.
+func (s *Server) When(pattern *RequestPattern) *Stub {
+	stub := &Stub{server: s, pattern: pattern, status: http.StatusOK, header: http.Header{}, times: -1}
+	s.lock.Lock()
+	s.stubs = append(s.stubs, stub)
+	s.lock.Unlock()
+	return stub
+}
+
+// Verify check all stubs are called as expected times(at least once if not set), and no unmatched requests.
+func (s *Server) Verify() {
+	s.t.Helper()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, stub := range s.stubs {
+		calls := len(stub.calls)
+		if stub.times < 0 && calls == 0 {
+			s.t.Errorf("stub %v was not called", stub.pattern)
+		} else if stub.times >= 0 && calls != stub.times {
+			s.t.Errorf("stub %v expected to be called %d times, actually %d times", stub.pattern, stub.times, calls)
+		}
+	}
+	for _, req := range s.unmatched {
+		s.t.Errorf("unexpected request: %v %v", req.Method, req.URL)
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	iox.Close(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	recorded := &RecordedRequest{Method: r.Method, URL: r.URL.String(), Header: r.Header, Body: body}
+
+	s.lock.Lock()
+	var stub *Stub
+	for i := len(s.stubs) - 1; i >= 0; i-- {
+		if s.stubs[i].pattern.match(r, body) {
+			stub = s.stubs[i]
+			break
+		}
+	}
+	if stub == nil {
+		s.unmatched = append(s.unmatched, recorded)
+		s.lock.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	stub.calls = append(stub.calls, recorded)
+	s.lock.Unlock()
+
+	if stub.handler != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		stub.handler(w, r)
+		return
+	}
+	for name, values := range stub.header {
+		w.Header()[name] = values
+	}
+	var reader io.Reader
+	if stub.body != nil {
+		if reader, err = stub.body.GetReader(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if w.Header().Get(httpx.HeaderContenttype) == "" && stub.body.MimeType() != "" {
+			w.Header().Set(httpx.HeaderContenttype, stub.body.MimeType())
+		}
+	}
+	w.WriteHeader(stub.status)
+	if reader != nil {
+		_, _ = io.Copy(w, reader)
+	}
+}
+
+// RequestPattern matches requests by method, path, and optional queries, headers and body
+type RequestPattern struct {
+	method  string
+	path    string
+	queries map[string]string
+	headers map[string]string
+	body    *string
+}
+
+// Request create a pattern match method and path. The path should not contain query, use Query to match queries.
+func Request(method string, path string) *RequestPattern {
+	return &RequestPattern{method: method, path: path, queries: map[string]string{}, headers: map[string]string{}}
+}
+
+// GET create a pattern match GET requests of path
+func GET(path string) *RequestPattern {
+	return Request(http.MethodGet, path)
+}
+
+// POST create a pattern match POST requests of path
+func POST(path string) *RequestPattern {
+	return Request(http.MethodPost, path)
+}
+
+// PUT create a pattern match PUT requests of path
+func PUT(path string) *RequestPattern {
+	return Request(http.MethodPut, path)
+}
+
+// PATCH create a pattern match PATCH requests of path
+func PATCH(path string) *RequestPattern {
+	return Request(http.MethodPatch, path)
+}
+
+// DELETE create a pattern match DELETE requests of path
+func DELETE(path string) *RequestPattern {
+	return Request(http.MethodDelete, path)
+}
+
+// Query add a query param should match
+func (p *RequestPattern) Query(name, value string) *RequestPattern {
+	p.queries[name] = value
+	return p
+}
+
+// Header add a header should match
+func (p *RequestPattern) Header(name, value string) *RequestPattern {
+	p.headers[name] = value
+	return p
+}
+
+// Body set the request body should match
+func (p *RequestPattern) Body(body string) *RequestPattern {
+	p.body = &body
+	return p
+}
+
+func (p *RequestPattern) match(r *http.Request, body []byte) bool {
+	if p.method != r.Method || p.path != r.URL.Path {
+		return false
+	}
+	query := r.URL.Query()
+	for name, value := range p.queries {
+		if query.Get(name) != value {
+			return false
+		}
+	}
+	for name, value := range p.headers {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	return p.body == nil || *p.body == string(body)
+}
+
+func (p *RequestPattern) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.method + " " + p.path)
+	for name, value := range p.queries {
+		sb.WriteString(fmt.Sprintf(" query %v=%v", name, value))
+	}
+	for name, value := range p.headers {
+		sb.WriteString(fmt.Sprintf(" header %v: %v", name, value))
+	}
+	return sb.String()
+}
+
+// Stub is the response for matched requests, and records the calls
+type Stub struct {
+	server  *Server
+	pattern *RequestPattern
+	status  int
+	header  http.Header
+	body    httpx.Body
+	handler http.HandlerFunc
+	times   int // expected call times, -1 means at least once
+
+	calls []*RecordedRequest // guarded by server lock
+}
+
+// Reply set the response status and body. The body can be nil.
+func (s *Stub) Reply(status int, body httpx.Body) *Stub {
+	s.status = status
+	s.body = body
+	return s
+}
+
+// ReplyFunc handle matched requests by handler
+func (s *Stub) ReplyFunc(handler http.HandlerFunc) *Stub {
+	s.handler = handler
+	return s
+}
+
+// WithHeader add a response header
+func (s *Stub) WithHeader(name, value string) *Stub {
+	s.header.Add(name, value)
+	return s
+}
+
+// Times set the expected call times, checked by Server.Verify
+func (s *Stub) Times(n int) *Stub {
+	s.times = n
+	return s
+}
+
+// Calls return the requests matched this stub
+func (s *Stub) Calls() []*RecordedRequest {
+	s.server.lock.Lock()
+	defer s.server.lock.Unlock()
+	return append([]*RecordedRequest(nil), s.calls...)
+}
+
+// JSON create a json body for reply
+func JSON(value interface{}) httpx.Body {
+	return httpx.NewJSONBody(value)
+}
+
+// Text create a plain text body for reply
+func Text(text string) httpx.Body {
+	return httpx.NewStringBody(text, httpx.MimeTypePlainText)
+}
@@ -0,0 +1,61 @@
+package httpxtest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hsiafan/glow/netx/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	user := server.When(GET("/users/1")).Reply(http.StatusOK, JSON(map[string]string{"name": "test"}))
+	created := server.When(POST("/users").Header("X-Token", "abc").Body(`{"name":"new"}`)).
+		Reply(http.StatusCreated, Text("created")).WithHeader("Location", "/users/2").Times(1)
+	server.When(GET("/search").Query("q", "go")).ReplyFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("result of " + r.URL.Query().Get("q")))
+	})
+
+	client := httpx.NewClient()
+	var value map[string]string
+	_, err := client.Get(server.URL + "/users/1").CheckStatus().DecodeJSON(&value)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", value["name"])
+
+	header, body, err := client.Post(server.URL+"/users", httpx.NewJSONBody(map[string]string{"name": "new"}),
+		httpx.SetHeader("X-Token", "abc")).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, header.StatusCode)
+	assert.Equal(t, "/users/2", header.Header.Get("Location"))
+	assert.Equal(t, "created", body)
+
+	_, body, err = client.Get(server.URL+"/search", httpx.SetQueries(httpx.NewParam("q", "go"))).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "result of go", body)
+
+	assert.Len(t, user.Calls(), 1)
+	assert.Equal(t, `{"name":"new"}`, string(created.Calls()[0].Body))
+	server.Verify()
+
+	// verify failures
+	fake := &fakeTB{TB: t}
+	verifyServer := &Server{Server: server.Server, t: fake}
+	verifyServer.When(GET("/never"))
+	verifyServer.When(GET("/once")).Times(1)
+	verifyServer.Verify()
+	assert.Equal(t, []string{
+		"stub GET /never was not called",
+		"stub GET /once expected to be called 1 times, actually 0 times",
+	}, fake.errors)
+}
+
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Invoker send a request, and return the response
+type Invoker func(req *http.Request) (*http.Response, error)
+
+// Interceptor intercept the sending of request. It can modify the request, observe or replace the response,
+// or return without calling next to short-circuit the sending.
+type Interceptor func(req *http.Request, next Invoker) (*http.Response, error)
+
+// Intercept add interceptors for all requests sent by this client.
+// Interceptors are called in the order they are added, client interceptors are called before request interceptors.
+// If retry policy is set, the interceptors are called for every attempt.
+func Intercept(interceptors ...Interceptor) ClientOption {
+	return func(client *Client) {
+		client.interceptors = append(client.interceptors, interceptors...)
+	}
+}
+
+type interceptorsKey struct{}
+
+// WithInterceptors add interceptors for one request, called after the client's interceptors.
+func WithInterceptors(interceptors ...Interceptor) RequestOption {
+	return func(r *http.Request) (error, *http.Request) {
+		ctx := r.Context()
+		existed, _ := ctx.Value(interceptorsKey{}).([]Interceptor)
+		all := make([]Interceptor, 0, len(existed)+len(interceptors))
+		all = append(append(all, existed...), interceptors...)
+		return nil, r.WithContext(context.WithValue(ctx, interceptorsKey{}, all))
+	}
+}
+
+// build the invoker which call all interceptors, then send request by http client
+func (c *Client) invoker(r *http.Request) Invoker {
+	return c.intercepted(r, c.client.Do)
+}
+
+// build the invoker which call all interceptors, then send request by invoker
+func (c *Client) intercepted(r *http.Request, invoker Invoker) Invoker {
+	requestInterceptors, _ := r.Context().Value(interceptorsKey{}).([]Interceptor)
+	for i := len(requestInterceptors) - 1; i >= 0; i-- {
+		invoker = chainInterceptor(requestInterceptors[i], invoker)
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		invoker = chainInterceptor(c.interceptors[i], invoker)
+	}
+	return invoker
+}
+
+func chainInterceptor(interceptor Interceptor, next Invoker) Invoker {
+	return func(req *http.Request) (*http.Response, error) {
+		return interceptor(req, next)
+	}
+}
@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Intercept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Trace")))
+	}))
+	defer server.Close()
+
+	var calls []string
+	client := NewClient(Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		calls = append(calls, "client")
+		req.Header.Set("X-Trace", "client")
+		resp, err := next(req)
+		calls = append(calls, "client done")
+		return resp, err
+	}))
+
+	_, body, err := client.Get(server.URL, WithInterceptors(func(req *http.Request, next Invoker) (*http.Response, error) {
+		calls = append(calls, "request")
+		req.Header.Set("X-Trace", req.Header.Get("X-Trace")+",request")
+		return next(req)
+	})).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "client,request", body)
+	assert.Equal(t, []string{"client", "request", "client done"}, calls)
+
+	// short-circuit
+	_, body, err = client.Get(server.URL, WithInterceptors(func(req *http.Request, next Invoker) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "", body)
+
+}
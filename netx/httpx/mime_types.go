@@ -8,10 +8,11 @@ const (
 	MimetypeFormEncoded = "application/x-www-form-urlencoded"
 	MimeTypeOctetStream = "application/octet-stream"
 
-	MimeTypePlainText  = "text/plain"
-	MimeTypeCSS        = "text/css"
-	MimeTypeHTML       = "text/html"
-	MimeTypeJavascript = "text/javascript"
+	MimeTypePlainText   = "text/plain"
+	MimeTypeCSS         = "text/css"
+	MimeTypeHTML        = "text/html"
+	MimeTypeJavascript  = "text/javascript"
+	MimeTypeEventStream = "text/event-stream"
 
 	MimeTypeMultipart = "multipart/form-data"
 
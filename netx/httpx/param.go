@@ -32,14 +32,21 @@ func ParseParam(str string) *Param {
 // EncodeParams encode params to encoded str.
 func EncodeParams(enc encoding.Encoding, params ...*Param) (string, error) {
 	var sb strings.Builder
-	if err := EncodeParamsTo(sb, enc, params...); err != nil {
+	if err := WriteParams(&sb, enc, params...); err != nil {
 		return "", err
 	}
 	return sb.String(), nil
 }
 
 // EncodeParamsTo encode params, and write to buf.
+//
+// Deprecated: buf is passed by value, the written data is lost. Use WriteParams instead.
 func EncodeParamsTo(buf strings.Builder, enc encoding.Encoding, params ...*Param) error {
+	return WriteParams(&buf, enc, params...)
+}
+
+// WriteParams encode params, and write to buf. If buf is not empty, a '&' is written before params.
+func WriteParams(buf *strings.Builder, enc encoding.Encoding, params ...*Param) error {
 	for _, param := range params {
 		if buf.Len() > 0 {
 			buf.WriteByte('&')
@@ -48,17 +55,43 @@ func EncodeParamsTo(buf strings.Builder, enc encoding.Encoding, params ...*Param
 		if err != nil {
 			return err
 		}
-		buf.WriteString(url.QueryEscape(name))
+		buf.WriteString(name)
 		buf.WriteByte('=')
 		value, err := EncodeQuery(param.Value, enc)
 		if err != nil {
 			return err
 		}
-		buf.WriteString(url.QueryEscape(value))
+		buf.WriteString(value)
 	}
 	return nil
 }
 
+// DecodeParams decode www-form-encoded str to params. The params key/value will be decoded using specified encoding.
+func DecodeParams(str string, enc encoding.Encoding) ([]*Param, error) {
+	var params []*Param
+	for _, item := range strings.Split(str, "&") {
+		if item == "" {
+			continue
+		}
+		var name, value string
+		if idx := strings.IndexByte(item, '='); idx >= 0 {
+			name, value = item[:idx], item[idx+1:]
+		} else {
+			name = item
+		}
+		name, err := DecodeQuery(name, enc)
+		if err != nil {
+			return nil, err
+		}
+		value, err = DecodeQuery(value, enc)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, &Param{Name: name, Value: value})
+	}
+	return params, nil
+}
+
 // EncodeQuery encode a single str token as http param key or value.
 func EncodeQuery(str string, enc encoding.Encoding) (string, error) {
 	if enc == nil || enc == unicode.UTF8 {
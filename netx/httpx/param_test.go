@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/simplifiedchinese"
+	"strings"
 	"testing"
 )
 
@@ -22,3 +23,16 @@ func TestEncodeQuery(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "%B2%E2%CA%D4", encoded)
 }
+
+func TestWriteParams(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("a=1")
+	assert.NoError(t, WriteParams(&sb, nil, NewParam("b", "2"), NewParam("c", "")))
+	assert.Equal(t, "a=1&b=2&c=", sb.String())
+}
+
+func TestEncodeParams(t *testing.T) {
+	encoded, err := EncodeParams(nil, NewParam("a b", "1&2"), NewParam("c", "测试"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a+b=1%262&c=%E6%B5%8B%E8%AF%95", encoded)
+}
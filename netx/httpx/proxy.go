@@ -0,0 +1,157 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ProxyRule choose the proxy url for the request target url, return empty string for connecting directly.
+// The proxy url format is the same as UseProxy.
+type ProxyRule func(target *url.URL) string
+
+// UseProxyRule choose proxy for every request by rule.
+func UseProxyRule(rule ProxyRule) ClientOption {
+	return func(client *Client) {
+		client.proxy = rule
+	}
+}
+
+// ProxyFromEnv set proxy by environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY, or the lowercase versions.
+// NO_PROXY is a comma-separated list of hosts not using proxy, each entry can be:
+// a domain name matches the domain and its sub domains("example.com"),
+// a domain name with leading dot matches only sub domains(".example.com"),
+// an ip address or a cidr("10.0.0.0/8"), optional with port("example.com:8080"), or "*" for all hosts.
+func ProxyFromEnv() ClientOption {
+	httpProxy := getEnv("HTTP_PROXY", "http_proxy")
+	httpsProxy := getEnv("HTTPS_PROXY", "https_proxy")
+	noProxy := parseNoProxy(getEnv("NO_PROXY", "no_proxy"))
+	return UseProxyRule(func(target *url.URL) string {
+		if noProxy.match(target) {
+			return ""
+		}
+		if target.Scheme == "https" {
+			return httpsProxy
+		}
+		return httpProxy
+	})
+}
+
+// ProxyAuth set username and password for proxies, if the proxy url does not contain one.
+// For http proxies basic auth is used, for socks5 proxies username/password auth is used.
+func ProxyAuth(username, password string) ClientOption {
+	return func(client *Client) {
+		client.proxyUser = url.UserPassword(username, password)
+	}
+}
+
+// used as http.Transport.Proxy. Socks5 proxies are dialed by transport's DialContext, which is the client dialer.
+func (c *Client) proxyURL(r *http.Request) (*url.URL, error) {
+	if c.proxy == nil {
+		return nil, nil
+	}
+	proxy := c.proxy(r.URL)
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil && c.proxyUser != nil {
+		u.User = c.proxyUser
+	}
+	return u, nil
+}
+
+func getEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+type noProxyEntry struct {
+	domain    string     // domain without leading dot
+	subdomain bool       // only matches sub domains
+	ip        net.IP     // may be nil
+	ipNet     *net.IPNet // may be nil
+	port      string     // empty for any port
+}
+
+type noProxyList struct {
+	all     bool
+	entries []noProxyEntry
+}
+
+func parseNoProxy(value string) *noProxyList {
+	list := &noProxyList{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if item == "*" {
+			list.all = true
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			list.entries = append(list.entries, noProxyEntry{ipNet: ipNet})
+			continue
+		}
+		var entry noProxyEntry
+		if host, port, err := net.SplitHostPort(item); err == nil {
+			item, entry.port = host, port
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			entry.ip = ip
+		} else {
+			if strings.HasPrefix(item, "*.") {
+				item = item[1:]
+			}
+			entry.subdomain = strings.HasPrefix(item, ".")
+			entry.domain = strings.TrimPrefix(item, ".")
+		}
+		list.entries = append(list.entries, entry)
+	}
+	return list
+}
+
+func (l *noProxyList) match(target *url.URL) bool {
+	if l.all {
+		return true
+	}
+	host := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[target.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range l.entries {
+		if entry.port != "" && entry.port != port {
+			continue
+		}
+		switch {
+		case entry.ipNet != nil:
+			if ip != nil && entry.ipNet.Contains(ip) {
+				return true
+			}
+		case entry.ip != nil:
+			if entry.ip.Equal(ip) {
+				return true
+			}
+		default:
+			if strings.HasSuffix(host, "."+entry.domain) || (!entry.subdomain && host == entry.domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package httpx
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseProxy(t *testing.T) {
+	// a forward proxy server, response the proxy auth and requested url
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Proxy-Authorization") + " " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	client := NewClient(UseProxy(proxy.Listener.Addr().String()), ProxyAuth("user", "pass"))
+	_, str, err := client.Get("http://example.invalid/path").ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz http://example.invalid/path", str)
+
+	client = NewClient(UseProxy("http://name:secret@" + proxy.Listener.Addr().String()))
+	_, str, err = client.Get("http://example.invalid/path").ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic bmFtZTpzZWNyZXQ= http://example.invalid/path", str)
+
+	client = NewClient(UseProxy("http://%zz"))
+	_, err = client.Get("http://example.invalid/path").DiscardBody()
+	assert.Error(t, err)
+}
+
+func TestUseProxyRule(t *testing.T) {
+	// a forward proxy server, response the proxy auth and requested url
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Proxy-Authorization") + " " + r.URL.String()))
+	}))
+	defer proxy.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer server.Close()
+
+	client := NewClient(UseProxyRule(func(target *url.URL) string {
+		if target.Hostname() == "example.invalid" {
+			return proxy.URL
+		}
+		return ""
+	}))
+	_, str, err := client.Get("http://example.invalid/").ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, " http://example.invalid/", str)
+	_, str, err = client.Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "direct", str)
+}
+
+func TestProxyFromEnv(t *testing.T) {
+	// a forward proxy server, response the proxy auth and requested url
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Proxy-Authorization") + " " + r.URL.String()))
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("NO_PROXY", "skip.invalid")
+
+	client := NewClient(ProxyFromEnv())
+	_, str, err := client.Get("http://example.invalid/").ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, " http://example.invalid/", str)
+	_, err = client.Get("http://www.skip.invalid/").DiscardBody()
+	assert.Error(t, err)
+}
+
+func TestNoProxy(t *testing.T) {
+	list := parseNoProxy(" example.com, .sub.org,*.star.net, 10.0.0.0/8, 192.168.1.1, local.dev:8080")
+	for target, expected := range map[string]bool{
+		"http://example.com/":        true,
+		"http://a.example.com/":      true,
+		"http://badexample.com/":     false,
+		"http://sub.org/":            false,
+		"http://a.sub.org/":          true,
+		"http://a.star.net/":         true,
+		"http://10.1.2.3/":           true,
+		"http://11.1.2.3/":           false,
+		"http://192.168.1.1:8000/":   true,
+		"http://local.dev:8080/":     true,
+		"http://local.dev/":          false,
+		"https://EXAMPLE.com:8443/x": true,
+	} {
+		u, err := url.Parse(target)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, list.match(u), target)
+	}
+	u, _ := url.Parse("http://any.host/")
+	assert.True(t, parseNoProxy("*").match(u))
+	assert.False(t, parseNoProxy("").match(u))
+}
+
+func TestSocks5Proxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSocks5(conn, "user", "pass")
+		}
+	}()
+
+	client := NewClient(UseProxy("socks5://"+listener.Addr().String()), ProxyAuth("user", "pass"))
+	_, str, err := client.Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", str)
+
+	client = NewClient(UseProxy("socks5://"+listener.Addr().String()), ProxyAuth("user", "wrong"))
+	_, err = client.Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+}
+
+// serve a socks5 connection, with username/password auth and connect command only
+func serveSocks5(conn net.Conn, username, password string) {
+	defer conn.Close()
+	buf := make([]byte, 256)
+	// greeting: version, methods
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte{5, 2})
+	// auth: version, username, password
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	user := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+		return
+	}
+	pass := make([]byte, buf[0])
+	if _, err := io.ReadFull(conn, pass); err != nil {
+		return
+	}
+	if string(user) != username || string(pass) != password {
+		_, _ = conn.Write([]byte{1, 1})
+		return
+	}
+	_, _ = conn.Write([]byte{1, 0})
+	// request: version, cmd, reserved, address type, address, port
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		host = net.IP(buf[:4]).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return
+		}
+		host = string(buf[:n])
+	default:
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go func() {
+		_, _ = io.Copy(target, conn)
+	}()
+	_, _ = io.Copy(conn, target)
+}
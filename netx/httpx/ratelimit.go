@@ -0,0 +1,178 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter. It is safe for concurrent use.
+type RateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64 // the bucket size
+
+	lock        sync.Mutex
+	tokens      float64   // may be negative, for reserved tokens
+	last        time.Time // the last time tokens updated
+	pausedUntil time.Time
+}
+
+// NewRateLimiter create a rate limiter allow rate requests per second, with burst at most.
+// If rate <= 0, not limit the rate. If burst < 1, 1 is used.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait block until a token is available, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Pause stop issuing tokens for d, such as when server response 429 Too Many Requests.
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// take a token, and return the duration need to wait for it
+func (l *RateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	l.advance(now)
+	if l.rate > 0 {
+		l.tokens--
+	}
+	var wait time.Duration
+	if l.tokens < 0 && l.rate > 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if paused := l.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+// give back a reserved token
+func (l *RateLimiter) cancel() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.advance(time.Now())
+	l.tokens = math.Min(l.tokens+1, l.burst)
+}
+
+func (l *RateLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.tokens+elapsed.Seconds()*l.rate, l.burst)
+		l.last = now
+	}
+}
+
+// RateLimit limit the rate of requests sent by this client, rate requests per second with burst.
+// When got a 429 response with Retry-After header, the limiter is paused for the duration.
+// Waiting for tokens respect the request context. If retry policy is set, every attempt takes a token.
+func RateLimit(rate float64, burst int) ClientOption {
+	limiter := NewRateLimiter(rate, burst)
+	return Intercept(newRateLimitInterceptor(func(req *http.Request) *RateLimiter {
+		return limiter
+	}))
+}
+
+// RateLimitPerHost limit the rate of requests to each host, rate requests per second with burst.
+// See RateLimit for details.
+func RateLimitPerHost(rate float64, burst int) ClientOption {
+	var lock sync.Mutex
+	limiters := map[string]*RateLimiter{}
+	return Intercept(newRateLimitInterceptor(func(req *http.Request) *RateLimiter {
+		lock.Lock()
+		defer lock.Unlock()
+		limiter, ok := limiters[req.URL.Host]
+		if !ok {
+			limiter = NewRateLimiter(rate, burst)
+			limiters[req.URL.Host] = limiter
+		}
+		return limiter
+	}))
+}
+
+func newRateLimitInterceptor(getLimiter func(req *http.Request) *RateLimiter) Interceptor {
+	return func(req *http.Request, next Invoker) (*http.Response, error) {
+		limiter := getLimiter(req)
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := next(req)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get(HeaderRetryAfter)); ok {
+				limiter.Pause(retryAfter)
+			}
+		}
+		return resp, err
+	}
+}
+
+// MaxInFlight limit the count of concurrent requests sent by this client.
+// A request is in flight until its response body is closed. Waiting respect the request context.
+// If n <= 0, not limit the count.
+func MaxInFlight(n int) ClientOption {
+	if n <= 0 {
+		return func(client *Client) {}
+	}
+	semaphore := make(chan struct{}, n)
+	return Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		select {
+		case semaphore <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		release := func() {
+			<-semaphore
+		}
+		resp, err := next(req)
+		if err != nil {
+			release()
+			return resp, err
+		}
+		if _, ok := resp.Body.(io.ReadWriteCloser); ok {
+			// upgraded connections, such as WebSocket, are not counted
+			release()
+			return resp, nil
+		}
+		resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+// call release when the body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
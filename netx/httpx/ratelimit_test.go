@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 15*time.Millisecond, elapsed)
+
+	limiter.Pause(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			w.Header().Set(HeaderRetryAfter, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(RateLimitPerHost(1000, 10))
+	header, err := client.Get(server.URL).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, header.StatusCode)
+
+	// paused by Retry-After
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Get(server.URL, WithContext(ctx)).DiscardBody()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), count)
+}
+
+func TestClient_MaxInFlight(t *testing.T) {
+	var current, max int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+	}))
+	defer server.Close()
+
+	client := NewClient(MaxInFlight(2))
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(server.URL).DiscardBody()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), max)
+
+	// not limited
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := NewClient(MaxInFlight(0)).Get(server.URL, WithContext(ctx)).DiscardBody()
+	assert.NoError(t, err)
+}
@@ -24,11 +24,8 @@ func SetQueriesWithEncoding(enc encoding.Encoding, params ...*Param) RequestOpti
 		}
 		url := r.URL
 		var sb strings.Builder
-		if url.RawQuery != "" {
-			sb.WriteString(url.RawQuery)
-			sb.WriteByte('&')
-		}
-		err := EncodeParamsTo(sb, enc, params...)
+		sb.WriteString(url.RawQuery)
+		err := WriteParams(&sb, enc, params...)
 		if err != nil {
 			return err, nil
 		}
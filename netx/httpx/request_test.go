@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Request(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Content-Length", r.Header.Get(HeaderContentLength))
+		w.Header().Set("X-Content-Type", r.Header.Get(HeaderContenttype))
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	header, body, err := client.Patch(server.URL, NewStringBody("test", MimeTypePlainText)).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", body)
+	assert.Equal(t, http.MethodPatch, header.Header.Get("X-Method"))
+	assert.Equal(t, "text/plain; charset=utf-8", header.Header.Get("X-Content-Type"))
+
+	req, err := client.newRequest(http.MethodPut, server.URL, NewBytesBody([]byte("data"), MimeTypeOctetStream))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), req.ContentLength)
+	assert.NotNil(t, req.GetBody)
+
+	req, err = client.newRequest(http.MethodPut, server.URL, NewBody(nil, MimeTypeOctetStream))
+	assert.NoError(t, err)
+	assert.Nil(t, req.GetBody)
+
+	// the body is re-sent on 307 redirect
+	header, body, err = client.Request("REPORT", server.URL+"/redirect", NewJSONBody(map[string]int{"a": 1})).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, body)
+	assert.Equal(t, "REPORT", header.Header.Get("X-Method"))
+
+	header, err = client.Options(server.URL).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodOptions, header.Header.Get("X-Method"))
+}
+
+func TestSetQueries(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/?a=1", nil)
+	err, r := SetQueries(NewParam("b", "2 3"), NewParam("c", ""))(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1&b=2+3&c=", r.URL.RawQuery)
+}
@@ -1,7 +1,11 @@
 package httpx
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -17,6 +21,7 @@ import (
 type ResponseHolder struct {
 	Response *http.Response
 	Err      error
+	Timings  *Timings // the timings of sending request, nil if trace is not enabled
 }
 
 // ResponseHeader is a http response without body...
@@ -73,16 +78,18 @@ func (r *ResponseHolder) ReadAllString() (*ResponseHeader, string, error) {
 	if r.Err != nil {
 		return nil, "", r.Err
 	}
-	enc := r.GetEncoding()
-	var reader io.Reader = r.Response.Body
 	defer iox.Close(r.Response.Body)
+	content, err := iox.ReadAllString(r.decodedBody())
+	return r.toResponseHeader(), content, err
+}
 
+// the body reader, which decode the body to utf-8 by the charset of response
+func (r *ResponseHolder) decodedBody() io.Reader {
+	enc := r.GetEncoding()
 	if enc != nil && enc != unicode.UTF8 {
-		reader = enc.NewDecoder().Reader(reader)
+		return enc.NewDecoder().Reader(r.Response.Body)
 	}
-
-	content, err := iox.ReadAllString(reader)
-	return r.toResponseHeader(), content, err
+	return r.Response.Body
 }
 
 // GetEncoding get encoding from response header. If header not set charset for content-type, return nil.
@@ -115,7 +122,7 @@ func (r *ResponseHolder) WriteToWriter(w io.Writer) (*ResponseHeader, error) {
 	return r.toResponseHeader(), err
 }
 
-// WriteToFile read all response body, write to file.
+// WriteToFile read all response body, write to file. For large files need resuming, use Client.Download.
 func (r *ResponseHolder) WriteToFile(path string) (*ResponseHeader, error) {
 	if r.Err != nil {
 		return nil, r.Err
@@ -135,18 +142,76 @@ func (r *ResponseHolder) DecodeJSON(v interface{}) (*ResponseHeader, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
-	enc := r.GetEncoding()
-	var reader io.Reader = r.Response.Body
 	defer iox.Close(r.Response.Body)
+	err := json.NewDecoder(r.decodedBody()).Decode(v)
+	return r.toResponseHeader(), err
+}
 
-	if enc != nil && enc != unicode.UTF8 {
-		reader = enc.NewDecoder().Reader(reader)
+// DecodeXML decode http body as xml, into a value.
+// If the response header not set charset, the encoding declared in xml is used.
+func (r *ResponseHolder) DecodeXML(v interface{}) (*ResponseHeader, error) {
+	if r.Err != nil {
+		return nil, r.Err
 	}
-
-	err := json.NewDecoder(reader).Decode(v)
+	defer iox.Close(r.Response.Body)
+	decoded := r.GetEncoding() != nil
+	decoder := xml.NewDecoder(r.decodedBody())
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if decoded {
+			// already decoded by the charset of response header
+			return input, nil
+		}
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	err := decoder.Decode(v)
 	return r.toResponseHeader(), err
 }
 
+// DecodeForm decode http body as www-form-urlencoded params.
+func (r *ResponseHolder) DecodeForm() (*ResponseHeader, []*Param, error) {
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	defer iox.Close(r.Response.Body)
+	content, err := iox.ReadAllString(r.Response.Body)
+	if err != nil {
+		return r.toResponseHeader(), nil, err
+	}
+	params, err := DecodeParams(strings.TrimSpace(content), r.GetEncoding())
+	return r.toResponseHeader(), params, err
+}
+
+// ForEachJSONLine read http body as JSON Lines(NDJSON) stream, and call consume for each json value.
+// Empty lines are skipped. If consume return an error, stop reading and return the error.
+func (r *ResponseHolder) ForEachJSONLine(consume func(value json.RawMessage) error) (*ResponseHeader, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	defer iox.Close(r.Response.Body)
+	reader := bufio.NewReader(r.decodedBody())
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return r.toResponseHeader(), err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if !json.Valid(line) {
+				return r.toResponseHeader(), fmt.Errorf("invalid json line: %s", line)
+			}
+			if cErr := consume(line); cErr != nil {
+				return r.toResponseHeader(), cErr
+			}
+		}
+		if err == io.EOF {
+			return r.toResponseHeader(), nil
+		}
+	}
+}
+
 func (r *ResponseHolder) toResponseHeader() *ResponseHeader {
 	if r.Response == nil {
 		return nil
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestResponseHolder_DecodeXML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := simplifiedchinese.GBK.NewEncoder().String(`<?xml version="1.0" encoding="GBK"?><user><name>测试</name></user>`)
+		w.Header().Set(HeaderContenttype, "application/xml")
+		_, _ = w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	var user struct {
+		XMLName xml.Name `xml:"user"`
+		Name    string   `xml:"name"`
+	}
+	_, err := NewClient().Get(server.URL).DecodeXML(&user)
+	assert.NoError(t, err)
+	assert.Equal(t, "测试", user.Name)
+}
+
+func TestResponseHolder_DecodeForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContenttype, MimetypeFormEncoded+"; charset=gbk")
+		_, _ = w.Write([]byte("name=%B2%E2%CA%D4&empty=&flag\n"))
+	}))
+	defer server.Close()
+
+	_, params, err := NewClient().Get(server.URL).DecodeForm()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Param{{"name", "测试"}, {"empty", ""}, {"flag", ""}}, params)
+}
+
+func TestResponseHolder_ForEachJSONLine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{\"id\":1}\n\n{\"id\":2}\n{\"id\":3}"))
+	}))
+	defer server.Close()
+
+	var ids []int
+	_, err := NewClient().Get(server.URL).ForEachJSONLine(func(value json.RawMessage) error {
+		var v struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		ids = append(ids, v.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	stop := errors.New("stop")
+	_, err = NewClient().Get(server.URL).ForEachJSONLine(func(value json.RawMessage) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
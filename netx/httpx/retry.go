@@ -0,0 +1,266 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hsiafan/glow/iox"
+	"github.com/hsiafan/glow/timex/durationx"
+)
+
+// RetryPolicy determine when and how to retry a failed request.
+// Zero value fields use the values of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts   int           // max attempts, including the first one
+	MinBackoff    time.Duration // the backoff before the first retry, doubled for each next retry
+	MaxBackoff    time.Duration // the max backoff
+	MaxRetryAfter time.Duration // if Retry-After header of response exceeds this, not retry
+	// RetryOn determine if should retry by the response or error. If nil, use DefaultRetryOn.
+	RetryOn func(resp *http.Response, err error) bool
+	// RetryNonIdempotent if retry non-idempotent requests, such as POST and PATCH.
+	// Requests with Idempotency-Key header are treated as idempotent.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy return a policy, retry at most 2 times, for connection errors, 5xx and 429 responses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:   3,
+		MinBackoff:    durationx.MilliSeconds(100),
+		MaxBackoff:    durationx.Seconds(10),
+		MaxRetryAfter: durationx.Minutes(1),
+		RetryOn:       DefaultRetryOn,
+	}
+}
+
+// DefaultRetryOn retry on connection errors, 5xx and 429(Too Many Requests) responses.
+// Other errors, such as invalid url, tls certificate verification failure, context canceled,
+// and errors returned by interceptors, are not retried.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return isConnectionError(err)
+	}
+	if resp == nil {
+		return false
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// if the error occurred when connecting or transferring data, such as connection refused, reset, or timeout
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	// url.Error implements net.Error, check the cause
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Retry set the retry policy for all requests sent by this client. If policy is nil, not retry.
+func Retry(policy *RetryPolicy) ClientOption {
+	return func(client *Client) {
+		client.retryPolicy = policy
+	}
+}
+
+type retryPolicyKey struct{}
+
+// WithRetry set the retry policy for one request, override the client's. If policy is nil, not retry.
+func WithRetry(policy *RetryPolicy) RequestOption {
+	return func(r *http.Request) (error, *http.Request) {
+		return nil, r.WithContext(context.WithValue(r.Context(), retryPolicyKey{}, policy))
+	}
+}
+
+// the retry policy for request
+func (c *Client) getRetryPolicy(r *http.Request) *RetryPolicy {
+	if policy, ok := r.Context().Value(retryPolicyKey{}).(*RetryPolicy); ok {
+		return policy
+	}
+	return c.retryPolicy
+}
+
+// send request, and retry if needed
+func (p *RetryPolicy) execute(r *http.Request, do Invoker) (*http.Response, error) {
+	d := DefaultRetryPolicy()
+	maxAttempts := intOrDefault(p.MaxAttempts, d.MaxAttempts)
+	retryOn := p.RetryOn
+	if retryOn == nil {
+		retryOn = d.RetryOn
+	}
+	ctx := r.Context()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := do(r)
+		if attempt >= maxAttempts || !p.canReplay(r) || !retryOn(resp, err) {
+			return resp, err
+		}
+
+		wait := p.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get(HeaderRetryAfter)); ok {
+				if retryAfter > durationOrDefault(p.MaxRetryAfter, d.MaxRetryAfter) {
+					return resp, err
+				}
+				wait = retryAfter
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// no time for next attempt
+			return resp, err
+		}
+		if resp != nil {
+			// drain body, so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			iox.Close(resp.Body)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if r, err = rewindRequest(r); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// if the request can be sent again
+func (p *RetryPolicy) canReplay(r *http.Request) bool {
+	if !p.RetryNonIdempotent && !isIdempotent(r) {
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// the jittered exponential backoff before retry
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := DefaultRetryPolicy()
+	minBackoff := durationOrDefault(p.MinBackoff, d.MinBackoff)
+	maxBackoff := durationOrDefault(p.MaxBackoff, d.MaxBackoff)
+	backoff := minBackoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	// equal jitter: half fixed, half random
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := r.Header["Idempotency-Key"]
+	return ok
+}
+
+// create a request for sending again, with the body got again
+func rewindRequest(r *http.Request) (*http.Request, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r, nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	nr := r.Clone(r.Context())
+	nr.Body = body
+	return nr, nil
+}
+
+// parse Retry-After header value, in seconds or http date form
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return durationx.Seconds(seconds), true
+	}
+	date, err := ParseDateHeader(value)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(date)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// a func to get body reader again. For bodies can only be read once, return nil.
+func bodyGetter(body Body) func() (io.ReadCloser, error) {
+	switch body.(type) {
+	case *ReaderBody, *MultiPartBody:
+		return nil
+	}
+	return func() (io.ReadCloser, error) {
+		reader, err := body.GetReader()
+		if err != nil {
+			return nil, err
+		}
+		if reader == nil {
+			return http.NoBody, nil
+		}
+		return io.NopCloser(reader), nil
+	}
+}
+
+func intOrDefault(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func durationOrDefault(value time.Duration, defaultValue time.Duration) time.Duration {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,127 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Retry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		n := atomic.AddInt32(&count, 1)
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if n == 2 {
+			w.Header().Set(HeaderRetryAfter, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}
+	client := NewClient(Retry(policy))
+
+	_, body, err := client.Put(server.URL, NewStringBody("test", MimeTypePlainText)).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "test", body)
+	assert.Equal(t, int32(3), count)
+
+	// not idempotent
+	atomic.StoreInt32(&count, 0)
+	header, err := client.Post(server.URL, NewStringBody("test", MimeTypePlainText)).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, header.StatusCode)
+	assert.Equal(t, int32(1), count)
+
+	// override by request option
+	atomic.StoreInt32(&count, 0)
+	header, err = client.Get(server.URL, WithRetry(nil)).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, header.StatusCode)
+
+	// the context deadline is not enough for waiting
+	atomic.StoreInt32(&count, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	header, err = client.Get(server.URL, WithContext(ctx), WithRetry(&RetryPolicy{MinBackoff: time.Minute})).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, header.StatusCode)
+	assert.Equal(t, int32(1), count)
+}
+
+func TestDefaultRetryOn(t *testing.T) {
+	var attempts int32
+	countAttempts := Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return next(req)
+	})
+	policy := &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}
+
+	// connection refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	_, err = NewClient(countAttempts, Retry(policy)).Get("http://" + addr).DiscardBody()
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.SwapInt32(&attempts, 0))
+
+	// tls verification failure
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, err = NewClient(countAttempts, Retry(policy)).Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.SwapInt32(&attempts, 0))
+
+	// error returned by interceptor
+	_, err = NewClient(countAttempts, Retry(policy), Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		return nil, errors.New("rejected")
+	})).Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.SwapInt32(&attempts, 0))
+
+	// invalid url
+	_, err = NewClient(countAttempts, Retry(policy)).Get("http://%zz").DiscardBody()
+	assert.Error(t, err)
+	assert.False(t, DefaultRetryOn(nil, err))
+	assert.True(t, DefaultRetryOn(nil, io.ErrUnexpectedEOF))
+	// an interceptor may return no response and no error
+	assert.False(t, DefaultRetryOn(nil, nil))
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	d, ok := parseRetryAfter("120")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter(FormatDateHeader(time.Now().Add(time.Hour)))
+	assert.True(t, ok)
+	assert.True(t, d > 59*time.Minute)
+
+	_, ok = parseRetryAfter("invalid")
+	assert.False(t, ok)
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for i := 0; i < 10; i++ {
+		d := policy.backoff(2)
+		assert.True(t, d >= 100*time.Millisecond && d <= 200*time.Millisecond)
+		d = policy.backoff(10)
+		assert.True(t, d >= 500*time.Millisecond && d <= time.Second)
+	}
+}
@@ -0,0 +1,197 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hsiafan/glow/iox"
+	"github.com/hsiafan/glow/timex/durationx"
+)
+
+// the default delay before reconnecting, if server not specify one by retry field
+var defaultEventRetry = durationx.Seconds(3)
+
+// the min delay before reconnecting, even if server specify a smaller one
+var minEventRetry = durationx.MilliSeconds(100)
+
+// Event is a Server-Sent Event
+type Event struct {
+	ID    string        // the last event id
+	Event string        // the event type, "message" if not set
+	Data  string        // the data, multi data lines are joined by '\n'
+	Retry time.Duration // the reconnection time set by this event, 0 if not set
+}
+
+// ForEachEvent read http body as Server-Sent Events stream, and call consume for each event.
+// If consume return an error, stop reading and return the error.
+func (r *ResponseHolder) ForEachEvent(consume func(event *Event) error) (*ResponseHeader, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	defer iox.Close(r.Response.Body)
+	return r.toResponseHeader(), readEvents(r.Response.Body, &eventState{}, consume)
+}
+
+// Events read http body as Server-Sent Events stream, and deliver events through a channel.
+// The events channel is closed when the stream ends, then the error channel receive one error, or is closed if stream ends normally.
+// To stop reading early, cancel the request context, then the body is closed, and the error channel receive the
+// context error.
+func (r *ResponseHolder) Events() (<-chan *Event, <-chan error) {
+	events := make(chan *Event)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		var ctx context.Context
+		if r.Err == nil {
+			ctx = r.Response.Request.Context()
+		}
+		if _, err := r.ForEachEvent(func(event *Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}); err != nil {
+			errs <- err
+		}
+	}()
+	return events, errs
+}
+
+// SubscribeEvents send a GET request to url for Server-Sent Events stream, and call consume for each event.
+// When disconnected, reconnect after the retry delay(at least 100ms), with Last-Event-ID header set to the last
+// received event id. Only connection errors cause reconnecting, other errors such as invalid url are returned.
+// It returns when ctx is done, consume return an error, server response 204 No Content, or other status besides 200.
+func (c *Client) SubscribeEvents(ctx context.Context, url string, consume func(event *Event) error,
+	options ...RequestOption) error {
+	state := &eventState{retry: defaultEventRetry}
+	for {
+		opts := []RequestOption{WithContext(ctx), SetHeader(HeaderAccept, MimeTypeEventStream),
+			SetHeader(HeaderCacheControl, "no-cache")}
+		if state.lastID != "" {
+			opts = append(opts, SetHeader(HeaderLastEventID, state.lastID))
+		}
+		holder := c.Get(url, append(opts, options...)...)
+
+		// connection and read errors are not returned, just reconnect
+		var consumeErr error
+		if holder.Err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var statusErr *StatusError
+			if errors.As(holder.Err, &statusErr) || !isConnectionError(holder.Err) {
+				return holder.Err
+			}
+		} else {
+			resp := holder.Response
+			if resp.StatusCode == http.StatusNoContent {
+				iox.Close(resp.Body)
+				return nil
+			}
+			if resp.StatusCode != http.StatusOK {
+				return holder.readStatusError()
+			}
+			_ = readEvents(resp.Body, state, func(event *Event) error {
+				consumeErr = consume(event)
+				return consumeErr
+			})
+			iox.Close(resp.Body)
+		}
+		if consumeErr != nil {
+			return consumeErr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := state.retry
+		if delay < minEventRetry {
+			delay = minEventRetry
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// the parse state kept across connections
+type eventState struct {
+	lastID string
+	retry  time.Duration
+}
+
+// parse event stream, dispatch events to consume
+func readEvents(r io.Reader, state *eventState, consume func(event *Event) error) error {
+	reader := bufio.NewReader(r)
+	var eventType string
+	var data bytes.Buffer
+	var hasData bool
+	var retry time.Duration
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && line == "" {
+			// incomplete event at the end of stream is discarded
+			return nil
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if hasData {
+				if eventType == "" {
+					eventType = "message"
+				}
+				event := &Event{ID: state.lastID, Event: eventType, Data: data.String(), Retry: retry}
+				if cErr := consume(event); cErr != nil {
+					return cErr
+				}
+			}
+			eventType, hasData, retry = "", false, 0
+			data.Reset()
+		} else if !strings.HasPrefix(line, ":") {
+			name, value := line, ""
+			if idx := strings.IndexByte(line, ':'); idx >= 0 {
+				name, value = line[:idx], strings.TrimPrefix(line[idx+1:], " ")
+			}
+			switch name {
+			case "event":
+				eventType = value
+			case "data":
+				if hasData {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+				hasData = true
+			case "id":
+				if !strings.ContainsRune(value, 0) {
+					state.lastID = value
+				}
+			case "retry":
+				if ms, pErr := strconv.ParseUint(value, 10, 32); pErr == nil {
+					retry = durationx.MilliSeconds(int(ms))
+					state.retry = retry
+				}
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readEvents(t *testing.T) {
+	stream := ": comment\n" +
+		"data: first\n\n" +
+		"event: update\r\nid: 1\ndata: line1\ndata:line2\n\n" +
+		"retry: 500\nid\n\n" +
+		"data: last\n\n" +
+		"data: incomplete"
+	var events []*Event
+	state := &eventState{}
+	err := readEvents(strings.NewReader(stream), state, func(event *Event) error {
+		events = append(events, event)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Event{
+		{Event: "message", Data: "first"},
+		{ID: "1", Event: "update", Data: "line1\nline2"},
+		{Event: "message", Data: "last"},
+	}, events)
+	assert.Equal(t, 500*time.Millisecond, state.retry)
+}
+
+func TestResponseHolder_Events(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContenttype, MimeTypeEventStream)
+		_, _ = w.Write([]byte("data: a\n\ndata: b\n\n"))
+	}))
+	defer server.Close()
+
+	events, errs := NewClient().Get(server.URL).Events()
+	var data []string
+	for event := range events {
+		data = append(data, event.Data)
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []string{"a", "b"}, data)
+}
+
+func TestResponseHolder_Events_stop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContenttype, MimeTypeEventStream)
+		_, _ = w.Write([]byte(strings.Repeat("data: a\n\n", 10)))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := NewClient().Get(server.URL, WithContext(ctx)).Events()
+	<-events
+	// stop reading, the reader goroutine should exit
+	cancel()
+	select {
+	case err := <-errs:
+		assert.True(t, errors.Is(err, context.Canceled), err)
+	case <-time.After(time.Second):
+		t.Error("the reader goroutine not exit")
+	}
+}
+
+func TestClient_SubscribeEvents(t *testing.T) {
+	var count int32
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get(HeaderLastEventID))
+		assert.Equal(t, MimeTypeEventStream, r.Header.Get(HeaderAccept))
+		switch atomic.AddInt32(&count, 1) {
+		case 1:
+			_, _ = w.Write([]byte("retry: 10\nid: 1\ndata: a\n\n"))
+		case 2:
+			_, _ = w.Write([]byte("id: 2\ndata: b\n\n"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	var data []string
+	err := NewClient().SubscribeEvents(context.Background(), server.URL, func(event *Event) error {
+		data = append(data, event.Data)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, data)
+	assert.Equal(t, []string{"", "1", "2"}, lastEventIDs)
+
+	// stop by consume error
+	atomic.StoreInt32(&count, 0)
+	stop := errors.New("stop")
+	err = NewClient().SubscribeEvents(context.Background(), server.URL, func(event *Event) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
+
+func TestClient_SubscribeEvents_errors(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("retry: 0\ndata: a\n\n"))
+	}))
+	defer server.Close()
+	consume := func(event *Event) error {
+		return nil
+	}
+
+	// not connection errors are returned at once
+	err := NewClient().SubscribeEvents(context.Background(), "http://%zz", consume)
+	assert.Error(t, err)
+	err = NewClient(CheckStatus()).SubscribeEvents(context.Background(), server.URL+"/error", consume)
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, int32(1), atomic.SwapInt32(&count, 0))
+
+	// reconnect delay is at least minEventRetry
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	err = NewClient().SubscribeEvents(ctx, server.URL, consume)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, atomic.LoadInt32(&count) <= 3, count)
+}
@@ -0,0 +1,125 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hsiafan/glow/iox"
+)
+
+// the max size of body kept in StatusError
+const maxStatusErrorBody = 1024
+
+// StatusError is the error for unexpected http response status
+type StatusError struct {
+	StatusCode int         // e.g. 404
+	Status     string      // e.g. "404 Not Found"
+	Header     http.Header // the response headers
+	Body       []byte      // the beginning of response body, truncated to at most 1024 bytes
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Body) == 0 {
+		return "unexpected http status: " + e.Status
+	}
+	return fmt.Sprintf("unexpected http status: %v, body: %s", e.Status, e.Body)
+}
+
+// CheckStatus check response status is 2xx for all requests sent by this client.
+// If not, the response body is closed, and the ResponseHolder.Err is set to a *StatusError.
+func CheckStatus() ClientOption {
+	return func(client *Client) {
+		client.checkStatus = true
+	}
+}
+
+type noCheckStatusKey struct{}
+
+// skip the status check of CheckStatus client option, for callers handle the status themselves
+func withoutCheckStatus() RequestOption {
+	return func(r *http.Request) (error, *http.Request) {
+		return nil, r.WithContext(context.WithValue(r.Context(), noCheckStatusKey{}, true))
+	}
+}
+
+// CheckStatus check the response status is 2xx. If not, close the response body, and set Err to a *StatusError.
+// Return the ResponseHolder self, for chaining calls like holder.CheckStatus().DecodeJSON(v).
+func (r *ResponseHolder) CheckStatus() *ResponseHolder {
+	if r.Err == nil && !isSuccessStatus(r.Response.StatusCode) {
+		r.Err = r.readStatusError()
+	}
+	return r
+}
+
+// ExpectStatus check the response status is one of codes. If not, close the response body, and set Err to a *StatusError.
+// Return the ResponseHolder self, for chaining calls like holder.ExpectStatus(200, 201).DecodeJSON(v).
+func (r *ResponseHolder) ExpectStatus(codes ...int) *ResponseHolder {
+	if r.Err != nil {
+		return r
+	}
+	for _, code := range codes {
+		if r.Response.StatusCode == code {
+			return r
+		}
+	}
+	r.Err = r.readStatusError()
+	return r
+}
+
+// DecodeJSONOrError decode http body as json. If response status is 2xx, decode into v;
+// else decode into errValue, and return a *StatusError.
+// If Err is already a *StatusError, such as set by CheckStatus, the errValue is decoded from its body,
+// which is truncated to 1024 bytes, so decoding larger error body fails.
+// If decoding errValue failed, the returned error wraps both the decode error and the *StatusError.
+func (r *ResponseHolder) DecodeJSONOrError(v interface{}, errValue interface{}) (*ResponseHeader, error) {
+	if statusErr, ok := r.Err.(*StatusError); ok {
+		if err := json.Unmarshal(statusErr.Body, errValue); err != nil {
+			return r.toResponseHeader(), fmt.Errorf("decode error body failed: %v, %w", err, statusErr)
+		}
+		return r.toResponseHeader(), statusErr
+	}
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if isSuccessStatus(r.Response.StatusCode) {
+		return r.DecodeJSON(v)
+	}
+	defer iox.Close(r.Response.Body)
+	data, err := io.ReadAll(r.decodedBody())
+	if err != nil {
+		return r.toResponseHeader(), err
+	}
+	statusErr := r.newStatusError(data)
+	if err := json.Unmarshal(data, errValue); err != nil {
+		return r.toResponseHeader(), fmt.Errorf("decode error body failed: %v, %w", err, statusErr)
+	}
+	return r.toResponseHeader(), statusErr
+}
+
+// read the beginning of body, close the body, and create StatusError
+func (r *ResponseHolder) readStatusError() *StatusError {
+	defer iox.Close(r.Response.Body)
+	var buf bytes.Buffer
+	_, _ = io.CopyN(&buf, r.decodedBody(), maxStatusErrorBody)
+	return r.newStatusError(buf.Bytes())
+}
+
+func (r *ResponseHolder) newStatusError(body []byte) *StatusError {
+	if len(body) > maxStatusErrorBody {
+		body = body[:maxStatusErrorBody]
+	}
+	return &StatusError{
+		StatusCode: r.Response.StatusCode,
+		Status:     r.Response.Status,
+		Header:     r.Response.Header,
+		Body:       body,
+	}
+}
+
+func isSuccessStatus(code int) bool {
+	return code >= 200 && code < 300
+}
@@ -0,0 +1,88 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseHolder_CheckStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContenttype, MimetypeJson)
+		if r.URL.Path == "/ok" {
+			_, _ = w.Write([]byte(`{"name":"test"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"not found"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	var value struct{ Name string }
+	_, err := client.Get(server.URL + "/ok").CheckStatus().DecodeJSON(&value)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", value.Name)
+
+	_, err = client.Get(server.URL + "/none").CheckStatus().DecodeJSON(&value)
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, `{"message":"not found"}`, string(statusErr.Body))
+	assert.Equal(t, MimetypeJson, statusErr.Header.Get(HeaderContenttype))
+
+	_, err = client.Get(server.URL + "/ok").ExpectStatus(http.StatusCreated).DiscardBody()
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusOK, statusErr.StatusCode)
+
+	// client option
+	client = NewClient(CheckStatus())
+	_, err = client.Get(server.URL + "/none").DiscardBody()
+	assert.True(t, errors.As(err, &statusErr))
+}
+
+func TestResponseHolder_DecodeJSONOrError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			_, _ = w.Write([]byte(`{"name":"test"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		if r.URL.Path == "/large" {
+			_, _ = w.Write([]byte(`{"message":"` + strings.Repeat("x", 2000) + `"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message":"bad request"}`))
+	}))
+	defer server.Close()
+
+	type value struct{ Name string }
+	type apiError struct{ Message string }
+	for _, client := range []*Client{NewClient(), NewClient(CheckStatus())} {
+		var v value
+		var apiErr apiError
+		_, err := client.Get(server.URL+"/ok").DecodeJSONOrError(&v, &apiErr)
+		assert.NoError(t, err)
+		assert.Equal(t, "test", v.Name)
+
+		header, err := client.Get(server.URL+"/bad").DecodeJSONOrError(&v, &apiErr)
+		var statusErr *StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusBadRequest, header.StatusCode)
+		assert.Equal(t, "bad request", apiErr.Message)
+	}
+
+	// the body kept by StatusError is truncated
+	var apiErr apiError
+	_, err := NewClient(CheckStatus()).Get(server.URL+"/large").DecodeJSONOrError(&value{}, &apiErr)
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Contains(t, err.Error(), "decode error body failed")
+	_, err = NewClient().Get(server.URL+"/large").DecodeJSONOrError(&value{}, &apiErr)
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Len(t, apiErr.Message, 2000)
+}
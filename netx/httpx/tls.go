@@ -0,0 +1,173 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadCertPool load PEM encoded certificates from files or directories, into a new cert pool.
+// For directories, files with extension .pem, .crt and .cer are loaded.
+func LoadCertPool(paths ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			files = files[:0]
+			for _, entry := range entries {
+				switch strings.ToLower(filepath.Ext(entry.Name())) {
+				case ".pem", ".crt", ".cer":
+					if !entry.IsDir() {
+						files = append(files, filepath.Join(path, entry.Name()))
+					}
+				}
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, errors.New("no valid certificates in file: " + file)
+			}
+		}
+	}
+	return pool, nil
+}
+
+// RootCAs set the root certificates for verifying server certificates, instead of the system roots.
+// Use LoadCertPool to load certificates from PEM files.
+func RootCAs(pool *x509.CertPool) ClientOption {
+	return func(client *Client) {
+		client.tlsConfig.RootCAs = pool
+	}
+}
+
+// ClientCertificate set the client certificate for mutual TLS, loaded from PEM encoded cert and key files.
+// The files are checked when creating new connections, and reloaded if changed.
+// Errors of loading files are returned when sending requests.
+func ClientCertificate(certFile, keyFile string) ClientOption {
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	return func(client *Client) {
+		client.tlsConfig.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return loader.get()
+		}
+	}
+}
+
+// MinTLSVersion set the min tls version, such as tls.VersionTLS12
+func MinTLSVersion(version uint16) ClientOption {
+	return func(client *Client) {
+		client.tlsConfig.MinVersion = version
+	}
+}
+
+// CipherSuites set the enabled cipher suites for TLS 1.2 and lower. TLS 1.3 cipher suites are not configurable.
+func CipherSuites(suites ...uint16) ClientOption {
+	return func(client *Client) {
+		client.tlsConfig.CipherSuites = suites
+	}
+}
+
+// ServerName set the server name for SNI and verifying server certificate, instead of the request host.
+func ServerName(name string) ClientOption {
+	return func(client *Client) {
+		client.tlsConfig.ServerName = name
+	}
+}
+
+// PinSPKI only accept servers, whose certificates SubjectPublicKeyInfo sha256 hash is one of pins.
+// The pin is base64 encoded, with optional "sha256/" prefix, like:
+// "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=".
+// With normal verification, any certificate of the verified chains(leaf, intermediates or root) can match the pins.
+// If DisableTLSVerify is set, only the leaf certificate can match, other certificates sent by server are not trusted.
+func PinSPKI(pins ...string) ClientOption {
+	pinSet := map[string]bool{}
+	for _, pin := range pins {
+		pinSet[strings.TrimPrefix(pin, "sha256/")] = true
+	}
+	return func(client *Client) {
+		client.tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			var certs []*x509.Certificate
+			if len(state.VerifiedChains) > 0 {
+				for _, chain := range state.VerifiedChains {
+					certs = append(certs, chain...)
+				}
+			} else if len(state.PeerCertificates) > 0 {
+				// the handshake only proves the server owns the leaf certificate key
+				certs = state.PeerCertificates[:1]
+			}
+			for _, cert := range certs {
+				if pinSet[SPKIHash(cert)] {
+					return nil
+				}
+			}
+			return fmt.Errorf("no certificate matches pinned public keys for %v", state.ServerName)
+		}
+	}
+}
+
+// SPKIHash return the base64 encoded sha256 hash of certificate SubjectPublicKeyInfo, for PinSPKI.
+func SPKIHash(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// load key pair, and reload when files changed
+type certLoader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // the latest modify time of the files
+}
+
+func (l *certLoader) get() (*tls.Certificate, error) {
+	modTime, err := latestModTime(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.cert != nil && modTime.Equal(l.modTime) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	l.cert, l.modTime = &cert, modTime
+	return l.cert, nil
+}
+
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
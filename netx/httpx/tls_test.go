@@ -0,0 +1,155 @@
+package httpx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCertPEM(t *testing.T, path string, cert *x509.Certificate) {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+// generate a self-signed cert
+func generateCert(t *testing.T, commonName string) ([]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return der, key
+}
+
+// generate a self-signed cert, and write cert and key files
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string) {
+	der, key := generateCert(t, commonName)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func TestLoadCertPool(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeCertPEM(t, filepath.Join(dir, "server.pem"), server.Certificate())
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a cert"), 0644))
+
+	_, err := NewClient().Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+
+	for _, path := range []string{dir, filepath.Join(dir, "server.pem")} {
+		pool, err := LoadCertPool(path)
+		assert.NoError(t, err)
+		_, err = NewClient(RootCAs(pool), MinTLSVersion(tls.VersionTLS12)).Get(server.URL).DiscardBody()
+		assert.NoError(t, err)
+	}
+
+	_, err = LoadCertPool(filepath.Join(dir, "README"))
+	assert.Error(t, err)
+	_, err = LoadCertPool(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestPinSPKI(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	pin := "sha256/" + SPKIHash(server.Certificate())
+	_, err := NewClient(RootCAs(pool), PinSPKI(pin)).Get(server.URL).DiscardBody()
+	assert.NoError(t, err)
+	_, err = NewClient(DisableTLSVerify(), PinSPKI(pin)).Get(server.URL).DiscardBody()
+	assert.NoError(t, err)
+
+	wrongPin := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	_, err = NewClient(RootCAs(pool), PinSPKI(wrongPin)).Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+}
+
+func TestPinSPKI_unverifiedChain(t *testing.T) {
+	pinnedDer, _ := generateCert(t, "pinned")
+	pinned, err := x509.ParseCertificate(pinnedDer)
+	assert.NoError(t, err)
+	leafDer, leafKey := generateCert(t, "attacker")
+
+	// the server sends the pinned certificate after its own leaf certificate
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leafDer, pinnedDer},
+		PrivateKey:  leafKey,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+
+	_, err = NewClient(DisableTLSVerify(), PinSPKI(SPKIHash(pinned))).Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+}
+
+func TestServerName(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.ServerName))
+	}))
+	server.StartTLS()
+	defer server.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	// the httptest server certificate is valid for example.com
+	_, str, err := NewClient(RootCAs(pool), ServerName("example.com")).Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", str)
+}
+
+func TestClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeKeyPair(t, certFile, keyFile, "first")
+
+	client := NewClient(DisableTLSVerify(), ClientCertificate(certFile, keyFile))
+	_, str, err := client.Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "first", str)
+
+	writeKeyPair(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Second)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	client.transport.CloseIdleConnections()
+	_, str, err = client.Get(server.URL).ReadAllString()
+	assert.NoError(t, err)
+	assert.Equal(t, "second", str)
+
+	_, err = NewClient(DisableTLSVerify()).Get(server.URL).DiscardBody()
+	assert.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timings is the time spent on each phase of sending a request. If retried, the phases are of the last attempt.
+type Timings struct {
+	DNS             time.Duration // dns lookup
+	Connect         time.Duration // tcp connect
+	TLSHandshake    time.Duration // tls handshake
+	TimeToFirstByte time.Duration // from start sending request, to the first byte of response received
+	Total           time.Duration // from start sending request, to response header received
+	ConnReused      bool          // if the connection is reused from the pool
+}
+
+func (t *Timings) String() string {
+	return fmt.Sprintf("dns=%v connect=%v tls=%v ttfb=%v total=%v reused=%v",
+		t.DNS, t.Connect, t.TLSHandshake, t.TimeToFirstByte, t.Total, t.ConnReused)
+}
+
+// Trace enable timings recording for all requests sent by this client. The timings are set to ResponseHolder.Timings.
+func Trace() ClientOption {
+	return func(client *Client) {
+		client.trace = true
+	}
+}
+
+type traceKey struct{}
+
+// WithTrace enable timings recording for one request. The timings are set to ResponseHolder.Timings.
+func WithTrace() RequestOption {
+	return func(r *http.Request) (error, *http.Request) {
+		return nil, r.WithContext(context.WithValue(r.Context(), traceKey{}, true))
+	}
+}
+
+func (c *Client) traceEnabled(r *http.Request) bool {
+	enabled, _ := r.Context().Value(traceKey{}).(bool)
+	return c.trace || enabled
+}
+
+// records timings by httptrace hooks. The hooks may be called by other goroutines.
+type timingsTracer struct {
+	lock    sync.Mutex
+	start   time.Time
+	timings Timings
+
+	dnsStart, connectStart, tlsStart time.Time
+}
+
+func newTimingsTracer() *timingsTracer {
+	return &timingsTracer{start: time.Now()}
+}
+
+func (t *timingsTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.record(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.record(func() { t.timings.DNS = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(network, addr string) {
+			t.record(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.record(func() { t.timings.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.record(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.record(func() { t.timings.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func() { t.timings.ConnReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func() { t.timings.TimeToFirstByte = time.Since(t.start) })
+		},
+	}
+}
+
+// reset for a new attempt
+func (t *timingsTracer) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.start = time.Now()
+	t.timings = Timings{}
+}
+
+func (t *timingsTracer) record(f func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	f()
+}
+
+// finish and return the timings
+func (t *timingsTracer) finish() *Timings {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	timings := t.timings
+	timings.Total = time.Since(t.start)
+	return &timings
+}
+
+// RequestMetrics is the metrics of one request attempt
+type RequestMetrics struct {
+	Host       string        // the request host
+	Method     string        // the request method
+	StatusCode int           // the response status code, 0 if failed
+	Err        error         // the error if failed
+	Duration   time.Duration // the time until response header received
+}
+
+// MetricsSink receive metrics of requests
+type MetricsSink func(m *RequestMetrics)
+
+// Metrics send metrics of every request attempt to sink.
+func Metrics(sink MetricsSink) ClientOption {
+	return Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next(req)
+		m := &RequestMetrics{Host: req.URL.Host, Method: req.Method, Err: err, Duration: time.Since(start)}
+		if resp != nil {
+			m.StatusCode = resp.StatusCode
+		}
+		sink(m)
+		return resp, err
+	})
+}
+
+// MetricsKey is the key of metrics aggregation
+type MetricsKey struct {
+	Host       string
+	Method     string
+	StatusCode int // 0 for failed requests
+}
+
+// MetricsValue is the aggregated metrics of requests
+type MetricsValue struct {
+	Count   int64         // the request count
+	Sum     time.Duration // the sum of durations
+	Buckets []int64       // the count of requests, with duration <= the corresponding bucket bound
+}
+
+// MemoryMetrics aggregates request metrics in memory, as counters and duration histograms.
+// Use it by Metrics(m.Observe).
+type MemoryMetrics struct {
+	bounds []time.Duration
+
+	lock   sync.Mutex
+	values map[MetricsKey]*MetricsValue
+}
+
+// NewMemoryMetrics create a MemoryMetrics with histogram bucket bounds. If not set, use default bounds
+// from 5ms to 10s.
+func NewMemoryMetrics(bounds ...time.Duration) *MemoryMetrics {
+	if len(bounds) == 0 {
+		for _, ms := range []int{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000} {
+			bounds = append(bounds, time.Duration(ms)*time.Millisecond)
+		}
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i] < bounds[j]
+	})
+	return &MemoryMetrics{bounds: bounds, values: map[MetricsKey]*MetricsValue{}}
+}
+
+// Observe record a request metrics
+func (mm *MemoryMetrics) Observe(m *RequestMetrics) {
+	key := MetricsKey{Host: m.Host, Method: m.Method, StatusCode: m.StatusCode}
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	value, ok := mm.values[key]
+	if !ok {
+		value = &MetricsValue{Buckets: make([]int64, len(mm.bounds))}
+		mm.values[key] = value
+	}
+	value.Count++
+	value.Sum += m.Duration
+	for i, bound := range mm.bounds {
+		if m.Duration <= bound {
+			value.Buckets[i]++
+		}
+	}
+}
+
+// Bounds return the histogram bucket bounds
+func (mm *MemoryMetrics) Bounds() []time.Duration {
+	return mm.bounds
+}
+
+// Snapshot return a copy of current metrics
+func (mm *MemoryMetrics) Snapshot() map[MetricsKey]MetricsValue {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	snapshot := make(map[MetricsKey]MetricsValue, len(mm.values))
+	for key, value := range mm.values {
+		v := *value
+		v.Buckets = append([]int64(nil), value.Buckets...)
+		snapshot[key] = v
+	}
+	return snapshot
+}
+
+// DumpCurl write a curl command equivalent to every request sent by this client to w, for debugging.
+func DumpCurl(w io.Writer) ClientOption {
+	var lock sync.Mutex
+	return Intercept(func(req *http.Request, next Invoker) (*http.Response, error) {
+		command, err := CurlCommand(req)
+		if err != nil {
+			command = "# " + err.Error()
+		}
+		lock.Lock()
+		_, _ = io.WriteString(w, command+"\n")
+		lock.Unlock()
+		return next(req)
+	})
+}
+
+// CurlCommand return a curl command equivalent to the request.
+// The body is included only if it can be read again by req.GetBody.
+func CurlCommand(req *http.Request) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("curl")
+	if req.Method != http.MethodGet {
+		sb.WriteString(" -X " + req.Method)
+	}
+	sb.WriteString(" " + shellQuote(req.URL.String()))
+	if req.Host != "" && req.Host != req.URL.Host {
+		sb.WriteString(" -H " + shellQuote("Host: "+req.Host))
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			sb.WriteString(" -H " + shellQuote(name+": "+value))
+		}
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			sb.WriteString(" # body can not be dumped")
+			return sb.String(), nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(body)
+		_ = body.Close()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" --data-binary " + shellQuote(string(data)))
+	}
+	return sb.String(), nil
+}
+
+// quote str by single quotes for posix shell
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
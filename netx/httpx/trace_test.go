@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Trace(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient(DisableTLSVerify())
+	holder := client.Get(server.URL, WithTrace())
+	_, err := holder.DiscardBody()
+	assert.NoError(t, err)
+	timings := holder.Timings
+	assert.NotNil(t, timings)
+	assert.False(t, timings.ConnReused)
+	assert.True(t, timings.Connect > 0)
+	assert.True(t, timings.TLSHandshake > 0)
+	assert.True(t, timings.TimeToFirstByte >= 5*time.Millisecond)
+	assert.True(t, timings.Total >= timings.TimeToFirstByte)
+
+	holder = client.Get(server.URL, WithTrace())
+	_, err = holder.DiscardBody()
+	assert.NoError(t, err)
+	assert.True(t, holder.Timings.ConnReused)
+
+	assert.Nil(t, client.Get(server.URL).Timings)
+}
+
+func TestClient_Trace_retry(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 2, MinBackoff: 100 * time.Millisecond, MaxBackoff: 100 * time.Millisecond}
+	holder := NewClient(Trace(), Retry(policy)).Get(server.URL)
+	header, err := holder.DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, header.StatusCode)
+	// the timings of the first attempt and the backoff are not included
+	assert.True(t, holder.Timings.Total < 50*time.Millisecond, holder.Timings.Total)
+	assert.True(t, holder.Timings.TimeToFirstByte <= holder.Timings.Total)
+}
+
+func TestClient_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewMemoryMetrics(time.Millisecond, time.Hour)
+	client := NewClient(Metrics(metrics.Observe))
+	for _, path := range []string{"/", "/", "/missing"} {
+		_, err := client.Get(server.URL + path).DiscardBody()
+		assert.NoError(t, err)
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	snapshot := metrics.Snapshot()
+	assert.Len(t, snapshot, 2)
+	ok := snapshot[MetricsKey{Host: host, Method: http.MethodGet, StatusCode: http.StatusOK}]
+	assert.Equal(t, int64(2), ok.Count)
+	assert.Equal(t, int64(2), ok.Buckets[1])
+	assert.Equal(t, int64(1), snapshot[MetricsKey{Host: host, Method: http.MethodGet, StatusCode: http.StatusNotFound}].Count)
+}
+
+func TestCurlCommand(t *testing.T) {
+	client := NewClient()
+	r, err := client.newRequest(http.MethodPost, "http://localhost/path?q=1", NewStringBody("it's", MimeTypePlainText))
+	assert.NoError(t, err)
+	r.Header.Set("X-Token", "abc")
+	command, err := CurlCommand(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -X POST 'http://localhost/path?q=1' -H 'Content-Type: text/plain; charset=utf-8' -H 'X-Token: abc' --data-binary 'it'\''s'`, command)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	var buf bytes.Buffer
+	_, err = NewClient(DumpCurl(&buf)).Get(server.URL).DiscardBody()
+	assert.NoError(t, err)
+	assert.Equal(t, "curl '"+server.URL+"'\n", buf.String())
+}
@@ -0,0 +1,540 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hsiafan/glow/iox"
+)
+
+// MessageType is the type of WebSocket data message
+type MessageType int
+
+// WebSocket message types
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// WebSocket close codes, defined in RFC 6455 section 7.4
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseAbnormalClosure    = 1006
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseMandatoryExtension = 1010
+	CloseInternalServerErr  = 1011
+)
+
+// frame opcodes
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+const (
+	websocketGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	permessageDeflate      = "permessage-deflate"
+	defaultWebSocketLimit  = 32 << 20
+	maxControlFramePayload = 125
+)
+
+// the tail removed from compressed message, and appended back before decompressing
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// CloseError is returned by WebSocketConn.ReadMessage when received a close frame from peer
+type CloseError struct {
+	Code   int    // the close code, CloseNoStatusReceived if the close frame has no code
+	Reason string // the close reason
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed: %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed: %d %v", e.Code, e.Reason)
+}
+
+// WebSocketProtocols set the sub protocols for WebSocket handshake.
+// The protocol selected by server can be got by WebSocketConn.Subprotocol.
+func WebSocketProtocols(protocols ...string) RequestOption {
+	return SetHeader(HeaderSecWebsocketProtocol, strings.Join(protocols, ", "))
+}
+
+// WebSocketCompression request permessage-deflate extension for WebSocket handshake.
+// The compression is used only if server accept it.
+func WebSocketCompression() RequestOption {
+	return SetHeader(HeaderSecWebsocketExtensions,
+		permessageDeflate+"; client_no_context_takeover; server_no_context_takeover")
+}
+
+// DialWebSocket connect to a WebSocket server. The url scheme should be ws, wss, http or https.
+// The connection is created by the dialer, tls config and proxy of this client, and cookies are sent if enabled.
+// The client's request timeout is used as handshake timeout. If server not accept the handshake with a 101 response,
+// a *StatusError is returned.
+func (c *Client) DialWebSocket(wsURL string, options ...RequestOption) (*WebSocketConn, error) {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, errors.New("unsupported websocket url scheme: " + u.Scheme)
+	}
+
+	r, err := c.newRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		r.Header.Set(HeaderUserAgent, c.userAgent)
+	}
+	for _, option := range options {
+		if err, r = option(r); err != nil {
+			return nil, err
+		}
+	}
+	key, err := newWebSocketKey()
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(HeaderUpgrade, "websocket")
+	r.Header.Set(HeaderConnection, "Upgrade")
+	r.Header.Set(HeaderSecWebsocketKey, key)
+	r.Header.Set(HeaderSecWebsocketVersion, "13")
+
+	if c.client.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), c.client.Timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+	// http.Client with timeout wraps the response body, then the upgraded connection can not be written
+	hc := &http.Client{Transport: c.client.Transport, Jar: c.client.Jar, CheckRedirect: c.client.CheckRedirect}
+	resp, err := c.intercepted(r, hc.Do)(r)
+	if err != nil {
+		return nil, err
+	}
+	holder := &ResponseHolder{Response: resp}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, holder.readStatusError()
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		iox.Close(resp.Body)
+		return nil, errors.New("websocket connection is not writable")
+	}
+	if !strings.EqualFold(resp.Header.Get(HeaderUpgrade), "websocket") ||
+		!headerContainsToken(resp.Header.Get(HeaderConnection), "upgrade") ||
+		resp.Header.Get(HeaderSecWebsocketAccept) != webSocketAccept(key) {
+		iox.Close(rwc)
+		return nil, errors.New("invalid websocket handshake response")
+	}
+	compress, err := parseDeflateExtension(resp.Header.Get(HeaderSecWebsocketExtensions))
+	if err != nil {
+		iox.Close(rwc)
+		return nil, err
+	}
+	return &WebSocketConn{
+		rwc:         rwc,
+		br:          bufio.NewReader(rwc),
+		header:      resp.Header,
+		subprotocol: resp.Header.Get(HeaderSecWebsocketProtocol),
+		compress:    compress,
+		readLimit:   defaultWebSocketLimit,
+	}, nil
+}
+
+// WebSocketConn is a client WebSocket connection.
+// One goroutine can read and other goroutines can write concurrently.
+type WebSocketConn struct {
+	rwc         io.ReadWriteCloser
+	br          *bufio.Reader
+	header      http.Header
+	subprotocol string
+	compress    bool
+	readLimit   int64
+	pongHandler func(data []byte)
+
+	writeLock sync.Mutex
+	closeSent bool
+}
+
+// Header return the headers of handshake response
+func (ws *WebSocketConn) Header() http.Header {
+	return ws.header
+}
+
+// Subprotocol return the sub protocol selected by server, empty if not any
+func (ws *WebSocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// Compressed return if permessage-deflate extension is used
+func (ws *WebSocketConn) Compressed() bool {
+	return ws.compress
+}
+
+// SetReadLimit set the max size of message can be read. Default is 32MB.
+func (ws *WebSocketConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// SetPongHandler set the handler called when received pong frames. Should be called before reading messages.
+func (ws *WebSocketConn) SetPongHandler(handler func(data []byte)) {
+	ws.pongHandler = handler
+}
+
+// ReadMessage read a data message. Ping frames are replied automatically.
+// If received a close frame, reply the close and return a *CloseError.
+func (ws *WebSocketConn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var compressed bool
+	var message []byte
+	for {
+		frame, err := readFrame(ws.br, ws.readLimit)
+		if err != nil {
+			return 0, nil, err
+		}
+		if frame.masked {
+			return 0, nil, ws.failf(CloseProtocolError, "server frame should not be masked")
+		}
+		if frame.rsv1 && (!ws.compress || frame.opcode == opContinuation || frame.opcode >= opClose) {
+			return 0, nil, ws.failf(CloseProtocolError, "unexpected rsv1 bit")
+		}
+		switch frame.opcode {
+		case opPing:
+			if err := ws.writeFrame(opPong, false, frame.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if ws.pongHandler != nil {
+				ws.pongHandler(frame.payload)
+			}
+			continue
+		case opClose:
+			closeErr := parseClosePayload(frame.payload)
+			code := closeErr.Code
+			if code == CloseNoStatusReceived {
+				code = CloseNormalClosure
+			}
+			_ = ws.CloseWithReason(code, "")
+			return 0, nil, closeErr
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, ws.failf(CloseProtocolError, "expect continuation frame")
+			}
+			messageType = MessageType(frame.opcode)
+			compressed = frame.rsv1
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, ws.failf(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, ws.failf(CloseProtocolError, "unknown opcode %d", frame.opcode)
+		}
+
+		if int64(len(message)+len(frame.payload)) > ws.readLimit {
+			return 0, nil, ws.failf(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, frame.payload...)
+		if !frame.fin {
+			continue
+		}
+		if compressed {
+			if message, err = inflate(message, ws.readLimit); err != nil {
+				return 0, nil, ws.failf(CloseInvalidPayloadData, "decompress message failed: %v", err)
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, ws.failf(CloseInvalidPayloadData, "invalid utf-8 text message")
+		}
+		return messageType, message, nil
+	}
+}
+
+// WriteMessage write a data message
+func (ws *WebSocketConn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("invalid message type: %d", messageType)
+	}
+	if !ws.compress {
+		return ws.writeFrame(byte(messageType), false, data)
+	}
+	compressed, err := deflate(data)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(byte(messageType), true, compressed)
+}
+
+// WriteText write a text message
+func (ws *WebSocketConn) WriteText(text string) error {
+	return ws.WriteMessage(TextMessage, []byte(text))
+}
+
+// Ping send a ping frame. The data should not longer than 125 bytes.
+func (ws *WebSocketConn) Ping(data []byte) error {
+	if len(data) > maxControlFramePayload {
+		return errors.New("ping data too long")
+	}
+	return ws.writeFrame(opPing, false, data)
+}
+
+// CloseWithReason send a close frame with code and reason. The connection is not closed,
+// the caller should continue reading until got the *CloseError replied by server, then call Close.
+func (ws *WebSocketConn) CloseWithReason(code int, reason string) error {
+	if len(reason) > maxControlFramePayload-2 {
+		return errors.New("close reason too long")
+	}
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+	return ws.writeFrame(opClose, false, payload)
+}
+
+// Close send a normal close frame if not sent yet, and close the underlying connection.
+func (ws *WebSocketConn) Close() error {
+	_ = ws.CloseWithReason(CloseNormalClosure, "")
+	return ws.rwc.Close()
+}
+
+// send close frame for protocol error, and return the error
+func (ws *WebSocketConn) failf(code int, format string, args ...interface{}) error {
+	_ = ws.CloseWithReason(code, "")
+	return fmt.Errorf("websocket: "+format, args...)
+}
+
+func (ws *WebSocketConn) writeFrame(opcode byte, rsv1 bool, payload []byte) error {
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+	if ws.closeSent {
+		return errors.New("websocket: close frame already sent")
+	}
+	if opcode == opClose {
+		ws.closeSent = true
+	}
+	var maskKey [4]byte
+	if _, err := rand.Read(maskKey[:]); err != nil {
+		return err
+	}
+	_, err := ws.rwc.Write(encodeFrame(opcode, rsv1, payload, maskKey[:]))
+	return err
+}
+
+type wsFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	masked  bool
+	payload []byte // unmasked
+}
+
+// read one frame
+func readFrame(r io.Reader, limit int64) (*wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	frame := &wsFrame{
+		fin:    head[0]&0x80 != 0,
+		rsv1:   head[0]&0x40 != 0,
+		opcode: head[0] & 0x0f,
+		masked: head[1]&0x80 != 0,
+	}
+	if head[0]&0x30 != 0 {
+		return nil, errors.New("websocket: unexpected rsv bits")
+	}
+	size := uint64(head[1] & 0x7f)
+	isControl := frame.opcode >= opClose
+	if isControl && (!frame.fin || size > maxControlFramePayload) {
+		return nil, errors.New("websocket: invalid control frame")
+	}
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if limit > 0 && size > uint64(limit) {
+		return nil, errors.New("websocket: frame too big")
+	}
+	var maskKey [4]byte
+	if frame.masked {
+		if _, err := io.ReadFull(r, maskKey[:]); err != nil {
+			return nil, err
+		}
+	}
+	frame.payload = make([]byte, size)
+	if _, err := io.ReadFull(r, frame.payload); err != nil {
+		return nil, err
+	}
+	if frame.masked {
+		maskBytes(maskKey[:], frame.payload)
+	}
+	return frame, nil
+}
+
+// encode a final frame. If maskKey is empty, the payload is not masked.
+func encodeFrame(opcode byte, rsv1 bool, payload []byte, maskKey []byte) []byte {
+	buf := make([]byte, 0, len(payload)+14)
+	b0 := 0x80 | opcode
+	if rsv1 {
+		b0 |= 0x40
+	}
+	var maskBit byte
+	if len(maskKey) > 0 {
+		maskBit = 0x80
+	}
+	buf = append(buf, b0)
+	switch size := len(payload); {
+	case size < 126:
+		buf = append(buf, maskBit|byte(size))
+	case size <= 0xffff:
+		buf = append(buf, maskBit|126, byte(size>>8), byte(size))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(size))
+		buf = append(append(buf, maskBit|127), ext[:]...)
+	}
+	buf = append(buf, maskKey...)
+	start := len(buf)
+	buf = append(buf, payload...)
+	if len(maskKey) > 0 {
+		maskBytes(maskKey, buf[start:])
+	}
+	return buf
+}
+
+func maskBytes(key []byte, data []byte) {
+	for i := range data {
+		data[i] ^= key[i%4]
+	}
+}
+
+func parseClosePayload(payload []byte) *CloseError {
+	if len(payload) < 2 {
+		return &CloseError{Code: CloseNoStatusReceived}
+	}
+	return &CloseError{Code: int(binary.BigEndian.Uint16(payload)), Reason: string(payload[2:])}
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+func inflate(data []byte, limit int64) ([]byte, error) {
+	// append the removed tail, and a final empty block to end the stream
+	data = append(append(data, deflateTail...), 0x01, 0x00, 0x00, 0xff, 0xff)
+	r := flate.NewReader(bytes.NewReader(data))
+	defer iox.Close(r)
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	result, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(result)) > limit {
+		return nil, errors.New("message too big")
+	}
+	return result, nil
+}
+
+// check the extensions accepted by server. Only permessage-deflate without context takeover is supported.
+func parseDeflateExtension(value string) (bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return false, nil
+	}
+	for _, ext := range strings.Split(value, ",") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != permessageDeflate {
+			return false, errors.New("unsupported websocket extension: " + strings.TrimSpace(ext))
+		}
+		serverNoTakeover := false
+		for _, param := range params[1:] {
+			name := strings.TrimSpace(strings.SplitN(param, "=", 2)[0])
+			switch name {
+			case "server_no_context_takeover":
+				serverNoTakeover = true
+			case "client_no_context_takeover", "server_max_window_bits":
+			default:
+				return false, errors.New("unsupported permessage-deflate parameter: " + name)
+			}
+		}
+		if !serverNoTakeover {
+			return false, errors.New("permessage-deflate without server_no_context_takeover is not supported")
+		}
+	}
+	return true, nil
+}
+
+func newWebSocketKey() (string, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key[:]), nil
+}
+
+func webSocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// if comma separated header value contains the token, case insensitive
+func headerContainsToken(value string, token string) bool {
+	for _, v := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
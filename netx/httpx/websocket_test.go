@@ -0,0 +1,143 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_DialWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocketEcho(t, w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ws, err := NewClient().DialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), WithContext(ctx),
+		WebSocketProtocols("chat", "json"))
+	assert.NoError(t, err)
+	// the handshake context not affect the connection
+	cancel()
+	defer ws.Close()
+	assert.Equal(t, "chat", ws.Subprotocol())
+	assert.False(t, ws.Compressed())
+
+	assert.NoError(t, ws.WriteText("hello"))
+	messageType, data, err := ws.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, TextMessage, messageType)
+	assert.Equal(t, "hello", string(data))
+
+	large := []byte(strings.Repeat("x", 70000))
+	assert.NoError(t, ws.WriteMessage(BinaryMessage, large))
+	messageType, data, err = ws.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, BinaryMessage, messageType)
+	assert.Equal(t, large, data)
+
+	var pong string
+	ws.SetPongHandler(func(data []byte) {
+		pong = string(data)
+	})
+	assert.NoError(t, ws.Ping([]byte("hi")))
+	// server send a ping, then echo our pong
+	assert.NoError(t, ws.WriteText("ping"))
+	_, data, err = ws.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "pong:p", string(data))
+	assert.Equal(t, "hi", pong)
+
+	assert.NoError(t, ws.WriteText("close"))
+	_, _, err = ws.ReadMessage()
+	var closeErr *CloseError
+	assert.True(t, errors.As(err, &closeErr))
+	assert.Equal(t, CloseNormalClosure, closeErr.Code)
+	assert.Equal(t, "bye", closeErr.Reason)
+	assert.Error(t, ws.WriteText("after close"))
+}
+
+func TestClient_DialWebSocket_compression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocketEcho(t, w, r)
+	}))
+	defer server.Close()
+
+	ws, err := NewClient().DialWebSocket(server.URL, WebSocketCompression())
+	assert.NoError(t, err)
+	defer ws.Close()
+	assert.True(t, ws.Compressed())
+
+	text := strings.Repeat("compressed ", 100)
+	assert.NoError(t, ws.WriteText(text))
+	_, data, err := ws.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, text, string(data))
+}
+
+func TestClient_DialWebSocket_rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := NewClient().DialWebSocket(server.URL)
+	var statusErr *StatusError
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+}
+
+// serve a echo websocket connection, close when received "close"
+func serveWebSocketEcho(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(HeaderUpgrade) != "websocket" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	compress := strings.HasPrefix(r.Header.Get(HeaderSecWebsocketExtensions), permessageDeflate)
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get(HeaderSecWebsocketKey)) + "\r\n")
+	if protocol := r.Header.Get(HeaderSecWebsocketProtocol); protocol != "" {
+		_, _ = rw.WriteString("Sec-WebSocket-Protocol: " + strings.Split(protocol, ",")[0] + "\r\n")
+	}
+	if compress {
+		_, _ = rw.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover\r\n")
+	}
+	_, _ = rw.WriteString("\r\n")
+	_ = rw.Flush()
+
+	reader := bufio.NewReader(conn)
+	for {
+		frame, err := readFrame(reader, 0)
+		if err != nil {
+			return
+		}
+		assert.True(t, frame.masked)
+		switch frame.opcode {
+		case opText, opBinary:
+			if string(frame.payload) == "close" {
+				_, _ = conn.Write(encodeFrame(opClose, false, []byte{0x03, 0xe8, 'b', 'y', 'e'}, nil))
+				continue
+			}
+			if string(frame.payload) == "ping" {
+				_, _ = conn.Write(encodeFrame(opPing, false, []byte("p"), nil))
+				continue
+			}
+			_, _ = conn.Write(encodeFrame(frame.opcode, frame.rsv1, frame.payload, nil))
+		case opPing:
+			_, _ = conn.Write(encodeFrame(opPong, false, frame.payload, nil))
+		case opPong:
+			_, _ = conn.Write(encodeFrame(opText, false, append([]byte("pong:"), frame.payload...), nil))
+		case opClose:
+			return
+		}
+	}
+}